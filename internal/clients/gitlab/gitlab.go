@@ -0,0 +1,262 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+// Package gitlab provides a minimal client for the parts of the GitLab REST
+// API which the provider needs to register, monitor and remove runners.
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RunnerType is the scope a runner is registered for.
+type RunnerType string
+
+// Defines values for RunnerType.
+const (
+	InstanceType RunnerType = "instance_type"
+	GroupType    RunnerType = "group_type"
+	ProjectType  RunnerType = "project_type"
+)
+
+// CreateRunnerOptions are the parameters for creating a runner.
+type CreateRunnerOptions struct {
+	RunnerType  RunnerType `json:"runner_type"`
+	GroupId     *int64     `json:"group_id,omitempty"`
+	ProjectId   *int64     `json:"project_id,omitempty"`
+	Description *string    `json:"description,omitempty"`
+}
+
+// Runner is a runner as returned by GitLab on creation.
+type Runner struct {
+	Id             int64      `json:"id"`
+	Token          string     `json:"token"`
+	TokenExpiresAt *time.Time `json:"token_expires_at,omitempty"`
+}
+
+// RunnerStatus is the connection status of a runner as seen by GitLab.
+type RunnerStatus string
+
+// Defines values for RunnerStatus.
+const (
+	StatusOnline         RunnerStatus = "online"
+	StatusOffline        RunnerStatus = "offline"
+	StatusStale          RunnerStatus = "stale"
+	StatusNeverContacted RunnerStatus = "never_contacted"
+)
+
+// RunnerDetails is a runner as returned by GitLab when it is looked up.
+type RunnerDetails struct {
+	Id          int64        `json:"id"`
+	Status      RunnerStatus `json:"status"`
+	ContactedAt *time.Time   `json:"contacted_at,omitempty"`
+}
+
+// RunnerToken is a runner authentication token as returned by GitLab when
+// the token is reset.
+type RunnerToken struct {
+	Token          string     `json:"token"`
+	TokenExpiresAt *time.Time `json:"token_expires_at,omitempty"`
+}
+
+// Error is returned for any response from GitLab with an unexpected status.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("GitLab API error (%d %s): %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// HttpRequestDoer performs HTTP requests.
+//
+// The standard http.Client implements this interface.
+type HttpRequestDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client talks to the REST API of a single GitLab instance.
+type Client struct {
+	// The API base URL, e.g. https://gitlab.com/api/v4/
+	baseURL *url.URL
+
+	// Access token sent in the PRIVATE-TOKEN header.
+	token string
+
+	// Doer for performing requests.
+	client HttpRequestDoer
+}
+
+// ClientOption allows setting custom parameters during construction.
+type ClientOption func(*Client) error
+
+// WithHTTPClient overrides the default Doer.
+func WithHTTPClient(doer HttpRequestDoer) ClientOption {
+	return func(c *Client) error {
+		c.client = doer
+		return nil
+	}
+}
+
+// NewClient creates a new Client for the GitLab instance at instanceURL.
+func NewClient(instanceURL string, token string, opts ...ClientOption) (*Client, error) {
+	baseURL, err := url.Parse(strings.TrimSuffix(instanceURL, "/") + "/api/v4/")
+	if err != nil {
+		return nil, err
+	}
+
+	client := Client{
+		baseURL: baseURL,
+		token:   token,
+	}
+	for _, o := range opts {
+		if err := o(&client); err != nil {
+			return nil, err
+		}
+	}
+	if client.client == nil {
+		client.client = &http.Client{}
+	}
+
+	return &client, nil
+}
+
+// CreateRunner creates a runner and returns its ID and authentication token.
+func (c *Client) CreateRunner(ctx context.Context, opts CreateRunnerOptions) (*Runner, error) {
+	var runner Runner
+	if err := c.do(ctx, http.MethodPost, "user/runners", opts, http.StatusCreated, &runner); err != nil {
+		return nil, err
+	}
+
+	return &runner, nil
+}
+
+// GetRunner returns the details of the runner with the given ID.
+func (c *Client) GetRunner(ctx context.Context, id int64) (*RunnerDetails, error) {
+	var runner RunnerDetails
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("runners/%d", id), nil, http.StatusOK, &runner); err != nil {
+		return nil, err
+	}
+
+	return &runner, nil
+}
+
+// DeleteRunner deletes the runner with the given ID.
+func (c *Client) DeleteRunner(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("runners/%d", id), nil, http.StatusNoContent, nil)
+}
+
+// ResetRunnerToken resets the authentication token of the runner identified by
+// its current token, and returns the new token. The current token is invalid
+// once this returns successfully.
+func (c *Client) ResetRunnerToken(ctx context.Context, token string) (*RunnerToken, error) {
+	body := map[string]string{"token": token}
+
+	var runnerToken RunnerToken
+	if err := c.do(ctx, http.MethodPost, "runners/reset_authentication_token", body, http.StatusCreated, &runnerToken); err != nil {
+		return nil, err
+	}
+
+	return &runnerToken, nil
+}
+
+// VerifyRunner checks the authentication token of a runner, and returns the
+// runner it belongs to. Only the token is needed to authenticate.
+func (c *Client) VerifyRunner(ctx context.Context, token string) (*Runner, error) {
+	body := map[string]string{"token": token}
+
+	var runner Runner
+	if err := c.do(ctx, http.MethodPost, "runners/verify", body, http.StatusOK, &runner); err != nil {
+		return nil, err
+	}
+
+	return &runner, nil
+}
+
+func (c *Client) do(
+	ctx context.Context,
+	method string,
+	path string,
+	body any,
+	expectedStatus int,
+	result any,
+) error {
+	var bodyReader io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		bodyReader = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL.JoinPath(path).String(), bodyReader)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("PRIVATE-TOKEN", c.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	rsp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rsp.Body.Close() }()
+
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return err
+	}
+
+	if rsp.StatusCode != expectedStatus {
+		return &Error{
+			StatusCode: rsp.StatusCode,
+			Message:    errorMessage(bodyBytes),
+		}
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(bodyBytes, result)
+}
+
+// errorMessage extracts the message from a GitLab error response, which is
+// either `{"message": ...}` or `{"error": ...}`, falling back to the raw body.
+func errorMessage(body []byte) string {
+	var payload struct {
+		Message any    `json:"message"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		if payload.Message != nil {
+			return fmt.Sprint(payload.Message)
+		}
+		if payload.Error != "" {
+			return payload.Error
+		}
+	}
+
+	return string(body)
+}
+
+// IsNotFound reports whether err is a 404 response from GitLab.
+func IsNotFound(err error) bool {
+	var gitlabErr *Error
+	return errors.As(err, &gitlabErr) && gitlabErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,218 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateRunner(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v4/user/runners" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		if token := r.Header.Get("PRIVATE-TOKEN"); token != "glpat-test" {
+			t.Errorf("unexpected PRIVATE-TOKEN: %q", token)
+		}
+
+		var opts CreateRunnerOptions
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+			t.Errorf("unable to decode request: %s", err)
+			return
+		}
+		if opts.RunnerType != GroupType || opts.GroupId == nil || *opts.GroupId != 7 {
+			t.Errorf("unexpected options: %+v", opts)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": 42, "token": "glrt-0123456789_abcdefXYZ", "token_expires_at": null}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL+"/", "glpat-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groupId := int64(7)
+	runner, err := client.CreateRunner(context.Background(), CreateRunnerOptions{
+		RunnerType: GroupType,
+		GroupId:    &groupId,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if runner.Id != 42 || runner.Token != "glrt-0123456789_abcdefXYZ" {
+		t.Errorf("unexpected runner: %+v", runner)
+	}
+}
+
+func TestCreateRunnerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message": "403 Forbidden"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "glpat-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.CreateRunner(context.Background(), CreateRunnerOptions{RunnerType: InstanceType})
+
+	gitlabErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("expected *Error, got %T: %v", err, err)
+	}
+	if gitlabErr.StatusCode != http.StatusForbidden || gitlabErr.Message != "403 Forbidden" {
+		t.Errorf("unexpected error: %+v", gitlabErr)
+	}
+}
+
+func TestGetRunner(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/v4/runners/42" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": 42, "status": "online", "contacted_at": "2024-08-23T23:23:23Z"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "glpat-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner, err := client.GetRunner(context.Background(), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runner.Id != 42 || runner.Status != StatusOnline || runner.ContactedAt == nil {
+		t.Errorf("unexpected runner: %+v", runner)
+	}
+}
+
+func TestDeleteRunner(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("unexpected method: %s", r.Method)
+		}
+
+		switch r.URL.Path {
+		case "/api/v4/runners/42":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "404 Not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "glpat-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.DeleteRunner(context.Background(), 42); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if err := client.DeleteRunner(context.Background(), 43); !IsNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestResetRunnerToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v4/runners/reset_authentication_token" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("unable to decode request: %s", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if body["token"] != "glrt-old" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message": "403 Forbidden"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"token": "glrt-new", "token_expires_at": "2024-08-23T23:23:23Z"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runnerToken, err := client.ResetRunnerToken(context.Background(), "glrt-old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runnerToken.Token != "glrt-new" || runnerToken.TokenExpiresAt == nil {
+		t.Errorf("unexpected token: %+v", runnerToken)
+	}
+
+	if _, err := client.ResetRunnerToken(context.Background(), "glrt-new"); err == nil {
+		t.Error("expected error for unknown token")
+	}
+}
+
+func TestVerifyRunner(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v4/runners/verify" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("unable to decode request: %s", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if body["token"] != "glrt-0123456789_abcdefXYZ" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message": "403 Forbidden"}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"id": 42, "token": "glrt-0123456789_abcdefXYZ", "token_expires_at": null}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner, err := client.VerifyRunner(context.Background(), "glrt-0123456789_abcdefXYZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runner.Id != 42 {
+		t.Errorf("unexpected runner: %+v", runner)
+	}
+
+	if _, err := client.VerifyRunner(context.Background(), "glrt-unknown"); err == nil {
+		t.Error("expected error for unknown token")
+	}
+}
@@ -5,15 +5,19 @@ package runrs
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"path"
 	"strings"
 	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/oapi-codegen/runtime"
 	openapi_types "github.com/oapi-codegen/runtime/types"
 )
@@ -886,3 +890,108 @@ func ParseUpdateResponse(rsp *http.Response) (*UpdateResponse, error) {
 
 	return response, nil
 }
+
+// Base64 encoded, gzipped, json marshaled Swagger object
+var swaggerSpec = []string{
+
+	"H4sIAAAAAAAC/+xYX28buRH/KgO2D/Fh/0Vycj7dky+OCxdBGzg2rneWIXG5s1omu+SG5NoWDH33Yshd",
+	"S7LkpC0KHw4wkocVZzic+c1vhkPfM6GbVitUzrLJPbOiwob7z/fGaEMfrdEtGifRL6MxM7dskb7/arBk",
+	"E/aXdG0k7S2kfvsFKa4i1tgF6Yd9zDoj1YKtVhEz+LWTBgs2uVpbDvrX0aCv888oHNlZGyVPVNfQvnda",
+	"KRROanXKZY0Fi9iZuuG1LI7NomtQORax49ogL5bv76R1lkXsVJtcFgUqFrFLJSquFn7nP7Q71Z2iz194",
+	"cY5fO7TOW3RoFK8DKrRHNm2NZN3v+6er0Gz4PMQYsb9J94Hn551S6OEs0AojW/KXTdjHLq+lgOOPZ1Bq",
+	"A0KrUi462gscrFSLGuHdWfruBD7rHPAORee0iUBpB65CCNYhmAeL5kYKTKZqqnpJSwfYCvgghFwqbpa0",
+	"ewkGSzTgNHALU7ZlbMoS+E13ILgCqazjdQ3SQa0Fr+slaDNVWsFSd94wGrjiFlo0IJ2FQguPPKcwr19V",
+	"zrV2kqaFFjZZSFfzPBG6SY0/Ke3NpwcJXFTS9h5OlbQRVPoWb9BE8IPS7gcf8y4eP4PhlICIPJw1XPEF",
+	"2plXHlRsAscWbCeqCG4r9J5P1ZQZXEjr0AAH08cNr/pIrlyF0vyXwQwG04ODaKoIn86id2UelOOgOH9I",
+	"hIZCg9U+axekt0mZOVhnOuHAYFtLwR1ab4wYo8tv2I2A1zlKB3jXausJNVW24XWNa575kMB2puQCEzhT",
+	"oAlHuNWmsBOQpU+w6dSjQ+ABtjiusG7nUcBzgQ441NI6ck57ltsEfkWwXdtqQ1Lb5RZdcF5bHLQi4Kro",
+	"l24rKSrg5mEbFvRrqhRvsPBBW94gVGiQqOu5TOrysaPzCPBOYOu8yCr+BWeCW5x7TiMvQJdTNf+COc9j",
+	"L0jgVBvAO071HcE8jgstvqCJZcMXOIcchW7Qwjwsz8JywqJHjXJTvFv3J14KXkoMyJFoQp2kP5lNGK9b",
+	"qXBScxd60E5rkcWu4bOTgRZ9pm6lq6TabBa+3JTAn6FT8muHvvO4irvHCixipTYNd2zCpHLjEbVmqWRD",
+	"jTd7cEgqhws05BHlZ9envjuREF4VWPKudhMIEMTWLWsEw1WhG69ysAVCZ7lUca7rvQg4/QXVkwd6aQQ6",
+	"d1wqLKA0utkHRAKfEOFqq87XxVUbUS7mUyUMd/io/I1NgzwNWRp+9b0glG4S3Lkgb5LKNfWBR7xAx2Vt",
+	"k61wF7VxcfZ6ND588/bHo59mPBcFlv/67fcno58N4c0oT/cbtkbZaBxnR/FofDEaT/z/3zdTWnCHsZMN",
+	"7rPdmXoX10e4weX5hy3vB2z6rrjUnYlpNuBqSS1y8/DOyL3HdoHUa5s5/jQ6LIsiLvPRUXz49kjER+LH",
+	"LH5dZliMeXl4ePRmyzBZiL4za3gVCnGgULRdr7vDxypiFkVnpFt+ohEnFDlv5eyBgzlyg+Z0cOTvv16w",
+	"KExUIQ6Srj0jrNiK7EpVatovtHJc+CSGOmJ5IwiU7SQcQyOF0cN17jSEC297FrDU8PoucyM5nL//RO7U",
+	"UqCyuHHGcctFhfEoyTylpPOom04ZyyJ2g8aGY7PkbTIiFd2i4q1kEzZOsmTMItZyV3k40q3e65dabX1E",
+	"1Bp9YZ0VbMLeGeQOWUgKWveLLpYDBKhcgDbcd1Kr9LPVaj2efm/03Jq4VqsdADflBJ/YdCYwxJkOPWVs",
+	"q5UNuR5lr5/NxYBPAQpvt9NKCTjMsv+bI2GefRKk/lTgYXwGDPPzKmJvnsOJYeoeRkzsFSPm+MJSIQfC",
+	"zQbCXZPsEQtTGkfIhQXuYeIH6e/WR5nOni3T5zSD0Gy9qWhDmg+fK83DBa0dlP718+dK8D31/FW4rmp0",
+	"uJvlk7BOrcrwBp1vTlf33wLj8vLshFFzZhPf4Ngw3Qw3zHa3iDag+N5ddP0H8i0gUcC23gvbnmBbtL9r",
+	"UNW+sOmle/0PfGq7PXy6bAv+n/YneCZK/fGDWTeg8r3B7PkIHxK12z5Hge7fiGcYobo2djr2gb2UyZOX",
+	"/MZzyxfCxkPr6pr4GWztq5IP9BdKKPAGa93Se74/t3/xhZfXJE2zxP+bjLMsS9nq+sGdb5adHR5Vxx/P",
+	"1hUXnkur69W/BwCAo0VZURcAAA==",
+}
+
+// GetSwagger returns the content of the embedded swagger specification file
+// or error if failed to decode
+func decodeSpec() ([]byte, error) {
+	zipped, err := base64.StdEncoding.DecodeString(strings.Join(swaggerSpec, ""))
+	if err != nil {
+		return nil, fmt.Errorf("error base64 decoding spec: %w", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(zipped))
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing spec: %w", err)
+	}
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(zr)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing spec: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+var rawSpec = decodeSpecCached()
+
+// a naive cached of a decoded swagger spec
+func decodeSpecCached() func() ([]byte, error) {
+	data, err := decodeSpec()
+	return func() ([]byte, error) {
+		return data, err
+	}
+}
+
+// Constructs a synthetic filesystem for resolving external references when loading openapi specifications.
+func PathToRawSpec(pathToFile string) map[string]func() ([]byte, error) {
+	res := make(map[string]func() ([]byte, error))
+	if len(pathToFile) > 0 {
+		res[pathToFile] = rawSpec
+	}
+
+	return res
+}
+
+// GetSwagger returns the Swagger specification corresponding to the generated code
+// in this file. The external references of Swagger specification are resolved.
+// The logic of resolving external references is tightly connected to "import-mapping" feature.
+// Externally referenced files must be embedded in the corresponding golang packages.
+// Urls can be supported but this task was out of the scope.
+func GetSwagger() (swagger *openapi3.T, err error) {
+	resolvePath := PathToRawSpec("")
+
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	loader.ReadFromURIFunc = func(loader *openapi3.Loader, url *url.URL) ([]byte, error) {
+		pathToFile := url.String()
+		pathToFile = path.Clean(pathToFile)
+		getSpec, ok := resolvePath[pathToFile]
+		if !ok {
+			err1 := fmt.Errorf("path not found: %s", pathToFile)
+			return nil, err1
+		}
+		return getSpec()
+	}
+	var specData []byte
+	specData, err = rawSpec()
+	if err != nil {
+		return
+	}
+	swagger, err = loader.LoadFromData(specData)
+	if err != nil {
+		return
+	}
+	return
+}
@@ -0,0 +1,227 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// reservedJWTClaims are set by the provider, and can't be configured, with
+// the attribute each of them is set from.
+var reservedJWTClaims = []struct {
+	name string
+	from string
+}{
+	{name: "exp", from: "`lifetime`"},
+	{name: "iat", from: "`lifetime`"},
+	{name: "scope", from: "`allowed_scopes` of the provider"},
+}
+
+// AccessTokenEphemeralResourceModel describes the ephemeral resource data
+// model.
+type AccessTokenEphemeralResourceModel struct {
+	Claims    types.Map    `tfsdk:"claims"`
+	Lifetime  types.String `tfsdk:"lifetime"`
+	Token     types.String `tfsdk:"token"`
+	ExpiresAt types.String `tfsdk:"expires_at"`
+}
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var (
+	_ ephemeral.EphemeralResource                   = &AccessTokenEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithConfigure      = &AccessTokenEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithValidateConfig = &AccessTokenEphemeralResource{}
+)
+
+// NewAccessTokenEphemeralResource creates a new AccessTokenEphemeralResource.
+func NewAccessTokenEphemeralResource() ephemeral.EphemeralResource {
+	return &AccessTokenEphemeralResource{}
+}
+
+// AccessTokenEphemeralResource defines the ephemeral resource implementation.
+type AccessTokenEphemeralResource struct {
+	auth *runrsAuth
+
+	// allowedScopes are the scopes of the scope claim.
+	allowedScopes []string
+}
+
+func (r *AccessTokenEphemeralResource) Metadata(
+	ctx context.Context,
+	req ephemeral.MetadataRequest,
+	resp *ephemeral.MetadataResponse,
+) {
+	resp.TypeName = req.ProviderTypeName + "_access_token"
+}
+
+func (r *AccessTokenEphemeralResource) Schema(
+	ctx context.Context,
+	req ephemeral.SchemaRequest,
+	resp *ephemeral.SchemaResponse,
+) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "JWT for the peripheral API, signed with the provider `token`, e.g. for " +
+			"the `http` provider or health checks which call runrs directly.",
+
+		Attributes: map[string]schema.Attribute{
+			"claims": schema.MapAttribute{
+				MarkdownDescription: "Additional claims of the JWT; `iss` defaults to `peripheral`, " +
+					"`exp` and `iat` are set from `lifetime`, and `scope` is set to the `allowed_scopes` of " +
+					"the provider",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"lifetime": schema.StringAttribute{
+				MarkdownDescription: "How long the JWT is valid, e.g. `15m`; defaults to `1h`",
+				Optional:            true,
+				Validators: []validator.String{
+					isDuration(),
+				},
+			},
+			"token": schema.StringAttribute{
+				MarkdownDescription: "Signed JWT",
+				Computed:            true,
+				Sensitive:           true,
+			},
+			"expires_at": schema.StringAttribute{
+				MarkdownDescription: "Time when the JWT expires",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (r *AccessTokenEphemeralResource) ValidateConfig(
+	ctx context.Context,
+	req ephemeral.ValidateConfigRequest,
+	resp *ephemeral.ValidateConfigResponse,
+) {
+	var claims types.Map
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("claims"), &claims)...)
+	if resp.Diagnostics.HasError() || claims.IsNull() || claims.IsUnknown() {
+		return
+	}
+
+	for _, claim := range reservedJWTClaims {
+		if _, ok := claims.Elements()[claim.name]; ok {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("claims").AtMapKey(claim.name),
+				"Invalid Attribute Value",
+				fmt.Sprintf("The %q claim is set from %s and can't be configured.", claim.name, claim.from),
+			)
+		}
+	}
+}
+
+func (r *AccessTokenEphemeralResource) Configure(
+	ctx context.Context,
+	req ephemeral.ConfigureRequest,
+	resp *ephemeral.ConfigureResponse,
+) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*peripheralProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Ephemeral Resource Configure Type",
+			fmt.Sprintf(
+				"Expected *peripheralProviderData, got: %T. Report this issue to the provider developers.",
+				req.ProviderData,
+			),
+		)
+		return
+	}
+
+	r.auth = providerData.auth
+	r.allowedScopes = providerData.allowedScopes
+}
+
+func (r *AccessTokenEphemeralResource) Open(
+	ctx context.Context,
+	req ephemeral.OpenRequest,
+	resp *ephemeral.OpenResponse,
+) {
+	var data AccessTokenEphemeralResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	lifetime := defaultJWTLifetime
+	if !data.Lifetime.IsNull() {
+		var err error
+		lifetime, err = time.ParseDuration(data.Lifetime.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("lifetime"),
+				"Invalid Duration",
+				fmt.Sprintf("Unable to parse lifetime: %s", err),
+			)
+			return
+		}
+	}
+
+	claimValues := map[string]string{}
+	resp.Diagnostics.Append(data.Claims.ElementsAs(ctx, &claimValues, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	claims := jwt.MapClaims{}
+	for name, value := range claimValues {
+		claims[name] = value
+	}
+
+	// The JWT can't do more than the provider itself may.
+	claims["scope"] = strings.Join(r.allowedScopes, " ")
+
+	// auth is nil while the token of the provider is unknown.
+	if r.auth == nil {
+		resp.Diagnostics.Append(unknownConfigDiagnostic())
+		return
+	}
+
+	secret, err := r.auth.secret(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to Obtain Secret",
+			fmt.Sprintf("Access tokens are signed with the secret of the provider: %s", err),
+		)
+		return
+	}
+
+	expiresAt := time.Now().Add(lifetime)
+
+	token, err := signJWT(secret, claims, expiresAt)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Token Encoding Error",
+			fmt.Sprintf("Failed to encode JWT token: %s", err),
+		)
+		return
+	}
+
+	data.Token = types.StringValue(token)
+	data.ExpiresAt = types.StringValue(expiresAt.UTC().Format(time.RFC3339))
+
+	tflog.Trace(ctx, fmt.Sprintf("signed access token which expires at %s", data.ExpiresAt.ValueString()))
+
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+}
@@ -0,0 +1,231 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// testAccessTokenConfig returns the config of the access token ephemeral
+// resource with the given claims and lifetime.
+func testAccessTokenConfig(t *testing.T, claims map[string]string, lifetime string) (tftypes.Object, *tfprotov6.DynamicValue) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	var schemaResp ephemeral.SchemaResponse
+	NewAccessTokenEphemeralResource().Schema(ctx, ephemeral.SchemaRequest{}, &schemaResp)
+	configType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	claimValues := map[string]tftypes.Value{}
+	for name, value := range claims {
+		claimValues[name] = tftypes.NewValue(tftypes.String, value)
+	}
+
+	values := map[string]tftypes.Value{}
+	for name, typ := range configType.AttributeTypes {
+		values[name] = tftypes.NewValue(typ, nil)
+	}
+	values["claims"] = tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, claimValues)
+	values["lifetime"] = tftypes.NewValue(tftypes.String, lifetime)
+
+	config, err := tfprotov6.NewDynamicValue(configType, tftypes.NewValue(configType, values))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return configType, &config
+}
+
+func TestAccessTokenEphemeralResourceOpen(t *testing.T) {
+	ctx := context.Background()
+	server := testProtocol6ProviderServer(t)
+
+	configType, config := testAccessTokenConfig(t, map[string]string{"sub": "health-check"}, "15m")
+
+	resp, err := server.OpenEphemeralResource(ctx, &tfprotov6.OpenEphemeralResourceRequest{
+		TypeName: "peripheral_access_token",
+		Config:   config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range resp.Diagnostics {
+		t.Errorf("unexpected diagnostic: %s: %s", d.Summary, d.Detail)
+	}
+
+	result, err := resp.Result.Unmarshal(configType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var attrs map[string]tftypes.Value
+	if err := result.As(&attrs); err != nil {
+		t.Fatal(err)
+	}
+
+	var token string
+	if err := attrs["token"].As(&token); err != nil {
+		t.Fatal(err)
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) {
+		return []byte(testRunrsSecret), nil
+	}); err != nil {
+		t.Fatalf("unable to verify token: %s", err)
+	}
+
+	if claims["iss"] != "peripheral" || claims["sub"] != "health-check" {
+		t.Errorf("unexpected claims: %v", claims)
+	}
+
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lifetime := time.Until(expiresAt.Time); lifetime > 15*time.Minute || lifetime < 14*time.Minute {
+		t.Errorf("unexpected lifetime: %s", lifetime)
+	}
+}
+
+func TestAccessTokenEphemeralResourceScope(t *testing.T) {
+	ctx := context.Background()
+
+	testCases := map[string]struct {
+		allowedScopes []string
+		expectedScope string
+	}{
+		"all scopes by default": {
+			expectedScope: "gitlab-runners:read gitlab-runners:write",
+		},
+		"read only": {
+			allowedScopes: []string{scopeRead},
+			expectedScope: "gitlab-runners:read",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			config := map[string]tftypes.Value{}
+			if testCase.allowedScopes != nil {
+				var scopes []tftypes.Value
+				for _, scope := range testCase.allowedScopes {
+					scopes = append(scopes, tftypes.NewValue(tftypes.String, scope))
+				}
+				config["allowed_scopes"] = tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, scopes)
+			}
+
+			server, diags := testConfigureProvider(t, config)
+			for _, d := range diags {
+				t.Fatalf("unable to configure provider: %s: %s", d.Summary, d.Detail)
+			}
+
+			configType, accessTokenConfig := testAccessTokenConfig(t, nil, "5m")
+
+			resp, err := server.OpenEphemeralResource(ctx, &tfprotov6.OpenEphemeralResourceRequest{
+				TypeName: "peripheral_access_token",
+				Config:   accessTokenConfig,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, d := range resp.Diagnostics {
+				t.Fatalf("unexpected diagnostic: %s: %s", d.Summary, d.Detail)
+			}
+
+			result, err := resp.Result.Unmarshal(configType)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var attrs map[string]tftypes.Value
+			if err := result.As(&attrs); err != nil {
+				t.Fatal(err)
+			}
+
+			var token string
+			if err := attrs["token"].As(&token); err != nil {
+				t.Fatal(err)
+			}
+
+			claims := jwt.MapClaims{}
+			if _, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) {
+				return []byte(testRunrsSecret), nil
+			}); err != nil {
+				t.Fatalf("unable to verify token: %s", err)
+			}
+
+			if claims["scope"] != testCase.expectedScope {
+				t.Errorf("expected scope claim %q, got %v", testCase.expectedScope, claims["scope"])
+			}
+		})
+	}
+}
+
+func TestAccessTokenEphemeralResourceValidateConfig(t *testing.T) {
+	ctx := context.Background()
+	server := testProtocol6ProviderServer(t)
+
+	testCases := map[string]struct {
+		claims        map[string]string
+		lifetime      string
+		expectedError string
+	}{
+		"valid": {
+			claims:   map[string]string{"iss": "ci", "aud": "runrs"},
+			lifetime: "5m",
+		},
+		"reserved claim": {
+			claims:        map[string]string{"exp": "0"},
+			lifetime:      "5m",
+			expectedError: "Invalid Attribute Value",
+		},
+		"scope claim": {
+			claims:        map[string]string{"scope": "gitlab-runners:write"},
+			lifetime:      "5m",
+			expectedError: "Invalid Attribute Value",
+		},
+		"invalid lifetime": {
+			lifetime:      "-5m",
+			expectedError: "Invalid Duration",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, config := testAccessTokenConfig(t, testCase.claims, testCase.lifetime)
+
+			resp, err := server.ValidateEphemeralResourceConfig(ctx, &tfprotov6.ValidateEphemeralResourceConfigRequest{
+				TypeName: "peripheral_access_token",
+				Config:   config,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var summaries []string
+			for _, d := range resp.Diagnostics {
+				if d.Severity == tfprotov6.DiagnosticSeverityError {
+					summaries = append(summaries, d.Summary)
+				}
+			}
+
+			switch {
+			case testCase.expectedError == "" && len(summaries) > 0:
+				t.Errorf("unexpected errors: %v", summaries)
+			case testCase.expectedError != "" && !strings.Contains(strings.Join(summaries, "\n"), testCase.expectedError):
+				t.Errorf("expected error %q, got %v", testCase.expectedError, summaries)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+// Operations recorded in the audit log.
+const (
+	auditCreate = "create"
+	auditUpdate = "update"
+	auditDelete = "delete"
+)
+
+// Outcomes recorded in the audit log.
+const (
+	auditSucceeded = "succeeded"
+	auditFailed    = "failed"
+)
+
+// auditRedacted replaces runner tokens in the audit log.
+const auditRedacted = "REDACTED"
+
+// auditLog appends a record of every change to runners in runrs to a JSONL
+// file.
+type auditLog struct {
+	path string
+	user string
+
+	mu sync.Mutex
+}
+
+// auditChange is the old and new value of a changed runner attribute.
+type auditChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// auditRecord is a line of the audit log.
+type auditRecord struct {
+	Timestamp string                 `json:"timestamp"`
+	Operation string                 `json:"operation"`
+	User      string                 `json:"user,omitempty"`
+	Uuid      string                 `json:"uuid,omitempty"`
+	Id        int32                  `json:"id"`
+	Url       string                 `json:"url"`
+	Changes   map[string]auditChange `json:"changes,omitempty"`
+	Outcome   string                 `json:"outcome"`
+	ErrorType string                 `json:"error_type,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+// newAuditLog returns an auditLog which appends to the file at path, and
+// fails if it can't be written.
+func newAuditLog(path string) (*auditLog, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+
+	l := &auditLog{path: path}
+	if current, err := user.Current(); err == nil {
+		l.user = current.Username
+	}
+
+	return l, nil
+}
+
+// record appends a record of the operation on the runner to the audit log.
+// err is the error of the request, and apiErr the error runrs answered
+// with.
+func (l *auditLog) record(
+	operation string,
+	runner *runrs.GitLabRunner,
+	changes map[string]auditChange,
+	err error,
+	apiErr *runrs.Error,
+) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	record := auditRecord{
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Operation: operation,
+		User:      l.user,
+		Id:        runner.Id,
+		Url:       runner.Url,
+		Changes:   changes,
+		Outcome:   auditSucceeded,
+	}
+	if runner.Uuid != nil {
+		record.Uuid = runner.Uuid.String()
+	}
+
+	switch {
+	case err != nil:
+		record.Outcome = auditFailed
+		record.Error = err.Error()
+	case apiErr != nil:
+		record.Outcome = auditFailed
+		record.ErrorType = string(apiErr.ErrType)
+		record.Error = apiErr.Msg
+	}
+
+	line, marshalErr := json.Marshal(record)
+	if marshalErr == nil {
+		marshalErr = l.append(append(line, '\n'))
+	}
+	if marshalErr != nil {
+		diags.AddWarning(
+			"Audit Log Error",
+			fmt.Sprintf(
+				"Unable to record %s of GitLabRunner with ID %d in %s: %s",
+				operation,
+				runner.Id,
+				l.path,
+				marshalErr,
+			),
+		)
+	}
+
+	return diags
+}
+
+// append writes the line to the end of the audit log at once, so that
+// records of concurrent operations don't interleave.
+func (l *auditLog) append(line []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(line)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// auditChanges returns the attributes which differ between the prior and the
+// planned runner, where prior is nil for created runners. Tokens are
+// redacted.
+func auditChanges(prior, planned *runrs.GitLabRunner) map[string]auditChange {
+	created := prior == nil
+	if created {
+		prior = &runrs.GitLabRunner{}
+	}
+
+	changes := map[string]auditChange{}
+
+	add := func(name string, oldValue, newValue any) {
+		if created {
+			oldValue = nil
+		}
+		if created || oldValue != newValue {
+			changes[name] = auditChange{Old: oldValue, New: newValue}
+		}
+	}
+
+	add("id", prior.Id, planned.Id)
+	add("url", prior.Url, planned.Url)
+	add("docker_image", prior.DockerImage, planned.DockerImage)
+	add("name", stringOrNil(prior.Name), stringOrNil(planned.Name))
+
+	// Tokens are compared before they are redacted.
+	if created || prior.Token != planned.Token {
+		changes["token"] = auditChange{Old: redactedOrNil(prior.Token), New: redactedOrNil(planned.Token)}
+	}
+
+	return changes
+}
+
+// stringOrNil returns the string s points to, or nil.
+func stringOrNil(s *string) any {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return *s
+}
+
+// redactedOrNil returns auditRedacted for a token, or nil if there is none.
+func redactedOrNil(token string) any {
+	if token == "" {
+		return nil
+	}
+	return auditRedacted
+}
@@ -0,0 +1,132 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	uuidpkg "github.com/google/uuid"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	auditLog, err := newAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uuid := uuidpkg.MustParse(testCachedRunnerUuid)
+	name, newName := "runner", "renamed"
+
+	created := runrs.GitLabRunner{
+		Uuid:        &uuid,
+		Id:          1,
+		Name:        &name,
+		Url:         "https://gitlab.com",
+		Token:       "glrt-test1_abcdefXYZ",
+		DockerImage: "alpine:latest",
+	}
+	updated := created
+	updated.Name = &newName
+	updated.Token = "glrt-test2_abcdefXYZ"
+
+	for _, record := range []struct {
+		operation string
+		runner    *runrs.GitLabRunner
+		changes   map[string]auditChange
+		err       error
+		apiErr    *runrs.Error
+	}{
+		{auditCreate, &created, auditChanges(nil, &created), nil, nil},
+		{auditUpdate, &updated, auditChanges(&created, &updated), nil, &runrs.Error{
+			ErrType: runrs.InternalError,
+			Msg:     "docker is unreachable",
+		}},
+		{auditDelete, &updated, nil, errors.New("connection refused"), nil},
+	} {
+		if diags := auditLog.record(record.operation, record.runner, record.changes, record.err, record.apiErr); diags.HasError() {
+			t.Fatalf("unexpected errors: %v", diags)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var records []map[string]any
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), "glrt-") {
+			t.Errorf("expected tokens to be redacted, got %s", scanner.Text())
+		}
+
+		var record map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		delete(record, "timestamp")
+		delete(record, "user")
+		records = append(records, record)
+	}
+
+	expected := []map[string]any{
+		{
+			"operation": "create",
+			"uuid":      testCachedRunnerUuid,
+			"id":        float64(1),
+			"url":       "https://gitlab.com",
+			"changes": map[string]any{
+				"id":           map[string]any{"old": nil, "new": float64(1)},
+				"url":          map[string]any{"old": nil, "new": "https://gitlab.com"},
+				"docker_image": map[string]any{"old": nil, "new": "alpine:latest"},
+				"name":         map[string]any{"old": nil, "new": "runner"},
+				"token":        map[string]any{"old": nil, "new": "REDACTED"},
+			},
+			"outcome": "succeeded",
+		},
+		{
+			"operation": "update",
+			"uuid":      testCachedRunnerUuid,
+			"id":        float64(1),
+			"url":       "https://gitlab.com",
+			"changes": map[string]any{
+				"name":  map[string]any{"old": "runner", "new": "renamed"},
+				"token": map[string]any{"old": "REDACTED", "new": "REDACTED"},
+			},
+			"outcome":    "failed",
+			"error_type": "InternalError",
+			"error":      "docker is unreachable",
+		},
+		{
+			"operation": "delete",
+			"uuid":      testCachedRunnerUuid,
+			"id":        float64(1),
+			"url":       "https://gitlab.com",
+			"outcome":   "failed",
+			"error":     "connection refused",
+		},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("unexpected records:\n%v\nexpected:\n%v", records, expected)
+	}
+}
+
+func TestAuditLogInvalidPath(t *testing.T) {
+	if _, err := newAuditLog(filepath.Join(t.TempDir(), "missing", "audit.jsonl")); err == nil {
+		t.Error("expected audit log in a missing directory to fail")
+	}
+}
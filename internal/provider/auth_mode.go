@@ -0,0 +1,147 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+)
+
+// Auth modes of the provider, which set how requests to runrs are
+// authenticated.
+const (
+	// authModeJWT signs JWTs with token, or the secret of token_command.
+	authModeJWT = "jwt"
+
+	// authModeOIDC sends or exchanges the ID token of oidc.
+	authModeOIDC = "oidc"
+
+	// authModeAPIKey sends api_key in the api_key_header, for runrs behind
+	// a reverse proxy which expects a static API key.
+	authModeAPIKey = "api_key"
+
+	// authModeNone sends no credentials, for local runrs without auth.
+	authModeNone = "none"
+)
+
+// authModes are the valid values of auth_mode.
+var authModes = []string{authModeJWT, authModeOIDC, authModeAPIKey, authModeNone}
+
+// defaultAPIKeyHeader is used when api_key_header is not set.
+const defaultAPIKeyHeader = "X-API-Key"
+
+// authModeAttributes are the attributes which configure the credentials of
+// each auth mode, and the attributes each of them requires.
+var authModeAttributes = map[string]struct {
+	allowed  []string
+	required []string
+}{
+	authModeJWT:    {allowed: []string{"token", "token_command"}},
+	authModeOIDC:   {allowed: []string{"oidc"}, required: []string{"oidc"}},
+	authModeAPIKey: {allowed: []string{"api_key", "api_key_header"}, required: []string{"api_key"}},
+	authModeNone:   {},
+}
+
+// authMode returns the auth mode of the configuration, which defaults to oidc
+// if the oidc block is set, and to jwt otherwise.
+func (m *peripheralProviderModel) authMode() string {
+	if !m.AuthMode.IsNull() {
+		return m.AuthMode.ValueString()
+	}
+	if !m.OIDC.IsNull() {
+		return authModeOIDC
+	}
+	return authModeJWT
+}
+
+// credentialAttributeNames are the attributes which configure the
+// credentials of any auth mode.
+var credentialAttributeNames = []string{"token", "token_command", "oidc", "api_key", "api_key_header"}
+
+// credentialAttributes returns the attributes of credentialAttributeNames.
+func (m *peripheralProviderModel) credentialAttributes() map[string]attr.Value {
+	return map[string]attr.Value{
+		"token":          m.Token,
+		"token_command":  m.TokenCommand,
+		"oidc":           m.OIDC,
+		"api_key":        m.APIKey,
+		"api_key_header": m.APIKeyHeader,
+	}
+}
+
+// credentialAttribute returns the attribute with the credentials of the auth
+// mode, which authentication failures are reported on.
+func (m *peripheralProviderModel) credentialAttribute() string {
+	switch mode := m.authMode(); mode {
+	case authModeJWT:
+		if !m.TokenCommand.IsNull() {
+			return "token_command"
+		}
+		return "token"
+	case authModeNone:
+		return "auth_mode"
+	default:
+		return mode
+	}
+}
+
+var _ provider.ConfigValidator = authModeValidator{}
+
+// authModeValidator validates that only the attributes of the auth mode are
+// set, and that those it requires are.
+type authModeValidator struct{}
+
+func (v authModeValidator) Description(ctx context.Context) string {
+	return "only the attributes of auth_mode may be set"
+}
+
+func (v authModeValidator) MarkdownDescription(ctx context.Context) string {
+	return "only the attributes of `auth_mode` may be set"
+}
+
+func (v authModeValidator) ValidateProvider(
+	ctx context.Context,
+	req provider.ValidateConfigRequest,
+	resp *provider.ValidateConfigResponse,
+) {
+	var data peripheralProviderModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() || data.AuthMode.IsUnknown() {
+		return
+	}
+
+	mode := data.authMode()
+	attributes, ok := authModeAttributes[mode]
+	if !ok {
+		// auth_mode itself reports invalid values.
+		return
+	}
+
+	values := data.credentialAttributes()
+	for _, name := range credentialAttributeNames {
+		set := !values[name].IsNull()
+
+		if set && !slices.Contains(attributes.allowed, name) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(name),
+				"Invalid Attribute Combination",
+				fmt.Sprintf("%s can't be set with auth_mode %q.", name, mode),
+			)
+		}
+
+		if !set && slices.Contains(attributes.required, name) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(name),
+				"Missing Attribute",
+				fmt.Sprintf("%s must be set with auth_mode %q.", name, mode),
+			)
+		}
+	}
+}
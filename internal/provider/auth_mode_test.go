@@ -0,0 +1,247 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestAuthModeValidator(t *testing.T) {
+	oidcType := tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+		"token_file":    tftypes.String,
+		"token_env":     tftypes.String,
+		"audience":      tftypes.String,
+		"exchange_path": tftypes.String,
+	}}
+	oidc := tftypes.NewValue(oidcType, map[string]tftypes.Value{
+		"token_file":    tftypes.NewValue(tftypes.String, nil),
+		"token_env":     tftypes.NewValue(tftypes.String, testOIDCTokenEnv),
+		"audience":      tftypes.NewValue(tftypes.String, nil),
+		"exchange_path": tftypes.NewValue(tftypes.String, nil),
+	})
+	noToken := tftypes.NewValue(tftypes.String, nil)
+
+	testCases := map[string]struct {
+		config            map[string]tftypes.Value
+		expectedSummaries []string
+	}{
+		"jwt by default": {},
+		"jwt": {
+			config: map[string]tftypes.Value{
+				"auth_mode": tftypes.NewValue(tftypes.String, "jwt"),
+			},
+		},
+		"jwt with api_key": {
+			config: map[string]tftypes.Value{
+				"api_key": tftypes.NewValue(tftypes.String, "s3cr3t"),
+			},
+			expectedSummaries: []string{"Invalid Attribute Combination"},
+		},
+		"oidc by default": {
+			config: map[string]tftypes.Value{
+				"token": noToken,
+				"oidc":  oidc,
+			},
+		},
+		"oidc with token": {
+			config: map[string]tftypes.Value{
+				"auth_mode": tftypes.NewValue(tftypes.String, "oidc"),
+				"oidc":      oidc,
+			},
+			expectedSummaries: []string{"Invalid Attribute Combination"},
+		},
+		"oidc without oidc": {
+			config: map[string]tftypes.Value{
+				"auth_mode": tftypes.NewValue(tftypes.String, "oidc"),
+				"token":     noToken,
+			},
+			expectedSummaries: []string{"Missing Attribute"},
+		},
+		"api_key": {
+			config: map[string]tftypes.Value{
+				"auth_mode":      tftypes.NewValue(tftypes.String, "api_key"),
+				"token":          noToken,
+				"api_key":        tftypes.NewValue(tftypes.String, "s3cr3t"),
+				"api_key_header": tftypes.NewValue(tftypes.String, "X-Runrs-Key"),
+			},
+		},
+		"api_key without api_key": {
+			config: map[string]tftypes.Value{
+				"auth_mode": tftypes.NewValue(tftypes.String, "api_key"),
+				"token":     noToken,
+			},
+			expectedSummaries: []string{"Missing Attribute"},
+		},
+		"api_key with token and oidc": {
+			config: map[string]tftypes.Value{
+				"auth_mode": tftypes.NewValue(tftypes.String, "api_key"),
+				"api_key":   tftypes.NewValue(tftypes.String, "s3cr3t"),
+				"oidc":      oidc,
+			},
+			expectedSummaries: []string{"Invalid Attribute Combination", "Invalid Attribute Combination"},
+		},
+		"none": {
+			config: map[string]tftypes.Value{
+				"auth_mode": tftypes.NewValue(tftypes.String, "none"),
+				"token":     noToken,
+			},
+		},
+		"none with token": {
+			config: map[string]tftypes.Value{
+				"auth_mode": tftypes.NewValue(tftypes.String, "none"),
+			},
+			expectedSummaries: []string{"Invalid Attribute Combination"},
+		},
+		"unknown auth_mode": {
+			config: map[string]tftypes.Value{
+				"auth_mode": tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+				"api_key":   tftypes.NewValue(tftypes.String, "s3cr3t"),
+			},
+		},
+		"invalid auth_mode": {
+			config: map[string]tftypes.Value{
+				"auth_mode": tftypes.NewValue(tftypes.String, "basic"),
+			},
+			expectedSummaries: []string{"Invalid Attribute Value Match"},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			server, err := providerserver.NewProtocol6WithError(New("test")())()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := server.ValidateProviderConfig(context.Background(), &tfprotov6.ValidateProviderConfigRequest{
+				Config: testProviderConfig(t, testCase.config),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var summaries []string
+			for _, diag := range resp.Diagnostics {
+				summaries = append(summaries, diag.Summary)
+			}
+			if len(summaries) != len(testCase.expectedSummaries) {
+				t.Fatalf("expected %v, got %v", testCase.expectedSummaries, resp.Diagnostics)
+			}
+			for i, summary := range summaries {
+				if summary != testCase.expectedSummaries[i] {
+					t.Errorf("expected %v, got %v", testCase.expectedSummaries, resp.Diagnostics)
+				}
+			}
+		})
+	}
+}
+
+func TestProviderConfigureAuthMode(t *testing.T) {
+	// A reverse proxy in front of runrs, which expects an API key in
+	// X-Runrs-Key, and answers for runrs that the nil UUID doesn't exist.
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("X-Runrs-Key") != "s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(proxy.Close)
+
+	// A local runrs without auth.
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(local.Close)
+
+	testCases := map[string]struct {
+		endpoint          string
+		config            map[string]tftypes.Value
+		expectedSummary   string
+		expectedAttribute string
+	}{
+		"api_key": {
+			endpoint: proxy.URL,
+			config: map[string]tftypes.Value{
+				"auth_mode":      tftypes.NewValue(tftypes.String, "api_key"),
+				"api_key":        tftypes.NewValue(tftypes.String, "s3cr3t"),
+				"api_key_header": tftypes.NewValue(tftypes.String, "X-Runrs-Key"),
+			},
+		},
+		"wrong api_key": {
+			endpoint: proxy.URL,
+			config: map[string]tftypes.Value{
+				"auth_mode":      tftypes.NewValue(tftypes.String, "api_key"),
+				"api_key":        tftypes.NewValue(tftypes.String, "wrong"),
+				"api_key_header": tftypes.NewValue(tftypes.String, "X-Runrs-Key"),
+			},
+			expectedSummary:   "Authentication Failed",
+			expectedAttribute: "api_key",
+		},
+		"none": {
+			endpoint: local.URL,
+			config: map[string]tftypes.Value{
+				"auth_mode": tftypes.NewValue(tftypes.String, "none"),
+			},
+		},
+		"none behind proxy": {
+			endpoint: proxy.URL,
+			config: map[string]tftypes.Value{
+				"auth_mode": tftypes.NewValue(tftypes.String, "none"),
+			},
+			expectedSummary:   "Authentication Failed",
+			expectedAttribute: "auth_mode",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			config := map[string]tftypes.Value{
+				"endpoint": tftypes.NewValue(tftypes.String, testCase.endpoint),
+				"token":    tftypes.NewValue(tftypes.String, nil),
+			}
+			for name, value := range testCase.config {
+				config[name] = value
+			}
+
+			_, diags := testConfigureProvider(t, config)
+
+			// The proxy also rejects the request for the spec of runrs, which
+			// only warns that its version is unknown.
+			var errors []*tfprotov6.Diagnostic
+			for _, diag := range diags {
+				if diag.Severity == tfprotov6.DiagnosticSeverityError {
+					errors = append(errors, diag)
+				}
+			}
+
+			if testCase.expectedSummary == "" {
+				if len(errors) > 0 {
+					t.Errorf("unexpected diagnostics: %v", errors)
+				}
+				return
+			}
+
+			if len(errors) != 1 || errors[0].Summary != testCase.expectedSummary ||
+				!errors[0].Attribute.Equal(tftypes.NewAttributePath().WithAttributeName(testCase.expectedAttribute)) {
+				t.Errorf("expected %q on %s, got %v", testCase.expectedSummary, testCase.expectedAttribute, errors)
+			}
+		})
+	}
+}
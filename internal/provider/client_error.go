@@ -0,0 +1,34 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// clientErrorDiagnostic returns the diagnostic for an error talking to
+// runrs, which points at the timeouts if the request timed out.
+func clientErrorDiagnostic(err error) diag.Diagnostic {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return diag.NewErrorDiagnostic(
+			"Client Timeout",
+			fmt.Sprintf(
+				"runrs didn't respond in time, got error: %s. Increase `request_timeout` of the "+
+					"provider or the `timeouts` of the resource if runrs is slow rather than unreachable.",
+				err,
+			),
+		)
+	}
+
+	return diag.NewErrorDiagnostic(
+		"Client Error",
+		fmt.Sprintf("Unable to talk to client, got error: %s", err),
+	)
+}
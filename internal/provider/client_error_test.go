@@ -0,0 +1,62 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+func TestClientErrorDiagnostic(t *testing.T) {
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(hanging.Close)
+
+	client, err := runrs.NewClientWithResponses(
+		hanging.URL,
+		runrs.WithHTTPClient(&http.Client{Timeout: 10 * time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, requestTimeoutErr := client.ListWithResponse(context.Background())
+	if requestTimeoutErr == nil {
+		t.Fatal("expected request to time out")
+	}
+
+	testCases := map[string]struct {
+		err             error
+		expectedSummary string
+	}{
+		"request timeout": {
+			err:             requestTimeoutErr,
+			expectedSummary: "Client Timeout",
+		},
+		"operation timeout": {
+			err:             context.DeadlineExceeded,
+			expectedSummary: "Client Timeout",
+		},
+		"other error": {
+			err:             errors.New("connection refused"),
+			expectedSummary: "Client Error",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			d := clientErrorDiagnostic(testCase.err)
+			if d.Summary() != testCase.expectedSummary {
+				t.Errorf("expected summary %q, got %q", testCase.expectedSummary, d.Summary())
+			}
+		})
+	}
+}
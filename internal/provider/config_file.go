@@ -0,0 +1,229 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is read when config_file is not set, and may be missing
+// unless profile is set.
+const defaultConfigFile = "~/.config/peripheral/config"
+
+// defaultProfile is used when profile is not set.
+const defaultProfile = "default"
+
+// profileEnvVar sets profile when it isn't set in the provider configuration.
+const profileEnvVar = "PERIPHERAL_PROFILE"
+
+// configProfile is a named profile of a config file, which configures the
+// provider for one runrs.
+type configProfile struct {
+	Endpoint  string            `yaml:"endpoint" toml:"endpoint"`
+	Endpoints []string          `yaml:"endpoints" toml:"endpoints"`
+	Token     string            `yaml:"token" toml:"token"`
+	TokenFile string            `yaml:"token_file" toml:"token_file"`
+	TLS       configProfileTLS  `yaml:"tls" toml:"tls"`
+	Claims    map[string]string `yaml:"claims" toml:"claims"`
+
+	// dir is the directory of the config file, which relative paths are
+	// relative to.
+	dir string
+}
+
+// configProfileTLS are the TLS settings of a profile.
+type configProfileTLS struct {
+	CAFile             string `yaml:"ca_file" toml:"ca_file"`
+	CertFile           string `yaml:"cert_file" toml:"cert_file"`
+	KeyFile            string `yaml:"key_file" toml:"key_file"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" toml:"insecure_skip_verify"`
+}
+
+// loadConfigProfile reads the named profile from the config file at path,
+// which is YAML or TOML. It returns nil if neither path nor name were
+// configured and the default config file or profile doesn't exist.
+func loadConfigProfile(path, name string) (*configProfile, error) {
+	explicit := path != "" || name != ""
+	if path == "" {
+		path = defaultConfigFile
+	}
+	if name == "" {
+		name = defaultProfile
+	}
+
+	path, err := expandHome(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := parseConfigFile(path, content)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+
+	profile, ok := profiles[name]
+	if !ok {
+		if !explicit {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("no profile %q in %s", name, path)
+	}
+
+	profile.dir = filepath.Dir(path)
+
+	return &profile, nil
+}
+
+// parseConfigFile parses the profiles of a config file. Files ending in
+// .yaml, .yml or .toml are parsed as such, and others as TOML if they are
+// valid TOML, or as YAML otherwise.
+func parseConfigFile(path string, content []byte) (map[string]configProfile, error) {
+	var profiles map[string]configProfile
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return profiles, yaml.Unmarshal(content, &profiles)
+	case ".toml":
+		return profiles, toml.Unmarshal(content, &profiles)
+	}
+
+	if err := toml.Unmarshal(content, &profiles); err == nil {
+		return profiles, nil
+	}
+
+	profiles = nil
+	if err := yaml.Unmarshal(content, &profiles); err != nil {
+		return nil, errors.New("neither valid TOML nor YAML")
+	}
+
+	return profiles, nil
+}
+
+// endpoints returns the endpoints of the profile.
+func (p *configProfile) endpoints() []string {
+	if len(p.Endpoints) > 0 {
+		return p.Endpoints
+	}
+	if p.Endpoint != "" {
+		return []string{p.Endpoint}
+	}
+	return nil
+}
+
+// token returns the token of the profile, which is read from token_file if
+// token isn't set.
+func (p *configProfile) token() (string, error) {
+	if p.Token != "" || p.TokenFile == "" {
+		return p.Token, nil
+	}
+
+	path, err := p.path(p.TokenFile)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(token)), nil
+}
+
+// tlsConfig returns the TLS configuration of the profile, or nil if the
+// defaults are used.
+func (p *configProfile) tlsConfig() (*tls.Config, error) {
+	if p.TLS == (configProfileTLS{}) {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// Only for runrs behind a self-signed certificate in development.
+		InsecureSkipVerify: p.TLS.InsecureSkipVerify,
+	}
+
+	if p.TLS.CAFile != "" {
+		path, err := p.path(p.TLS.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", path)
+		}
+	}
+
+	if p.TLS.CertFile != "" || p.TLS.KeyFile != "" {
+		certFile, err := p.path(p.TLS.CertFile)
+		if err != nil {
+			return nil, err
+		}
+		keyFile, err := p.path(p.TLS.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// path resolves a path of the profile, which may start with ~ or be relative
+// to the config file.
+func (p *configProfile) path(path string) (string, error) {
+	path, err := expandHome(path)
+	if err != nil {
+		return "", err
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(p.dir, path)
+	}
+
+	return path, nil
+}
+
+// expandHome replaces a leading ~ in path with the home directory.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
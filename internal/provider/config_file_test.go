@@ -0,0 +1,184 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+
+	"terraform-provider-peripheral/internal/runrstest"
+)
+
+func TestLoadConfigProfile(t *testing.T) {
+	const yamlConfig = `
+default:
+  endpoint: http://localhost:3000
+staging:
+  endpoints:
+    - https://runrs-1.staging.example.com
+    - https://runrs-2.staging.example.com
+  token_file: staging.token
+  claims:
+    sub: ci
+`
+	const tomlConfig = `
+[default]
+endpoint = "http://localhost:3000"
+
+[staging]
+endpoints = ["https://runrs-1.staging.example.com", "https://runrs-2.staging.example.com"]
+token_file = "staging.token"
+
+[staging.claims]
+sub = "ci"
+`
+
+	testCases := map[string]struct {
+		file          string
+		content       string
+		profile       string
+		expected      *configProfile
+		expectedError bool
+	}{
+		"yaml": {
+			file:    "config.yaml",
+			content: yamlConfig,
+			profile: "staging",
+			expected: &configProfile{
+				Endpoints: []string{"https://runrs-1.staging.example.com", "https://runrs-2.staging.example.com"},
+				TokenFile: "staging.token",
+				Claims:    map[string]string{"sub": "ci"},
+			},
+		},
+		"toml": {
+			file:    "config.toml",
+			content: tomlConfig,
+			profile: "staging",
+			expected: &configProfile{
+				Endpoints: []string{"https://runrs-1.staging.example.com", "https://runrs-2.staging.example.com"},
+				TokenFile: "staging.token",
+				Claims:    map[string]string{"sub": "ci"},
+			},
+		},
+		"yaml without extension": {
+			file:     "config",
+			content:  yamlConfig,
+			expected: &configProfile{Endpoint: "http://localhost:3000"},
+		},
+		"toml without extension": {
+			file:     "config",
+			content:  tomlConfig,
+			expected: &configProfile{Endpoint: "http://localhost:3000"},
+		},
+		"missing profile": {
+			file:          "config.yaml",
+			content:       yamlConfig,
+			profile:       "production",
+			expectedError: true,
+		},
+		"invalid": {
+			file:          "config",
+			content:       "[default\nendpoint: =",
+			profile:       "default",
+			expectedError: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, testCase.file)
+			if err := os.WriteFile(path, []byte(testCase.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			profile, err := loadConfigProfile(path, testCase.profile)
+
+			if testCase.expectedError {
+				if err == nil {
+					t.Errorf("expected error, got %+v", profile)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			testCase.expected.dir = dir
+			if !reflect.DeepEqual(profile, testCase.expected) {
+				t.Errorf("expected %+v, got %+v", testCase.expected, profile)
+			}
+		})
+	}
+}
+
+func TestLoadConfigProfileDefault(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	profile, err := loadConfigProfile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile != nil {
+		t.Errorf("expected no profile without a config file, got %+v", profile)
+	}
+
+	if _, err := loadConfigProfile("", "staging"); err == nil {
+		t.Error("expected a missing config file to fail for an explicit profile")
+	}
+}
+
+func TestProviderConfigureProfile(t *testing.T) {
+	server := runrstest.NewServer(testRunrsSecret)
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.toml")
+	config := "[staging]\nendpoint = \"" + server.URL + "\"\ntoken_file = \"staging.token\"\n"
+	if err := os.WriteFile(configFile, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "staging.token"), []byte(testRunrsSecret+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		profile         string
+		expectedSummary string
+	}{
+		"staging": {
+			profile: "staging",
+		},
+		"missing profile": {
+			profile:         "production",
+			expectedSummary: "Invalid Config File",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, diags := testConfigureProvider(t, map[string]tftypes.Value{
+				"endpoint":    tftypes.NewValue(tftypes.String, nil),
+				"token":       tftypes.NewValue(tftypes.String, nil),
+				"config_file": tftypes.NewValue(tftypes.String, configFile),
+				"profile":     tftypes.NewValue(tftypes.String, testCase.profile),
+			})
+
+			if testCase.expectedSummary == "" {
+				if len(diags) > 0 {
+					t.Errorf("unexpected diagnostics: %v", diags)
+				}
+				return
+			}
+
+			if len(diags) != 1 || diags[0].Summary != testCase.expectedSummary {
+				t.Errorf("expected %q, got %v", testCase.expectedSummary, diags)
+			}
+		})
+	}
+}
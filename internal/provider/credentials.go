@@ -0,0 +1,71 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	uuidpkg "github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+// validateCredentials makes one authenticated request to runrs, so that a
+// wrong endpoint or token fails Configure once instead of every resource
+// operation. Rejected credentials are reported on attribute.
+func validateCredentials(
+	ctx context.Context,
+	client *runrs.ClientWithResponses,
+	endpoints []string,
+	attribute string,
+) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	// No runner has the nil UUID, so runrs answers 404 Not Found if the
+	// token is accepted.
+	apiResp, err := client.ReadWithResponse(withOperation(ctx, "read"), uuidpkg.Nil)
+	if err != nil {
+		diags.AddError(
+			"Endpoint Unreachable",
+			fmt.Sprintf(
+				"Unable to reach runrs at %s: %s\n\n"+
+					"Check the endpoint, or set skip_credentials_validation for offline plans.",
+				strings.Join(endpoints, ", "),
+				err,
+			),
+		)
+		return diags
+	}
+
+	switch apiResp.StatusCode() {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		// These statuses aren't in the spec, so the client doesn't decode
+		// their errors.
+		msg := strings.TrimSpace(string(apiResp.Body))
+		var apiErr runrs.Error
+		if json.Unmarshal(apiResp.Body, &apiErr) == nil && apiErr.Msg != "" {
+			msg = apiErr.Msg
+		}
+
+		diags.AddAttributeError(
+			path.Root(attribute),
+			"Authentication Failed",
+			fmt.Sprintf(
+				"runrs rejected the credentials of %s: %s (%s)\n\n"+
+					"Check that they match the configuration of runrs.",
+				attribute,
+				msg,
+				apiResp.Status(),
+			),
+		)
+	}
+
+	return diags
+}
@@ -0,0 +1,76 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+
+	"terraform-provider-peripheral/internal/runrstest"
+)
+
+func TestProviderConfigureCredentials(t *testing.T) {
+	server := runrstest.NewServer(testRunrsSecret)
+	t.Cleanup(server.Close)
+
+	unreachable := runrstest.NewServer(testRunrsSecret)
+	unreachable.Close()
+
+	testCases := map[string]struct {
+		config          map[string]tftypes.Value
+		expectedSummary string
+		expectedPath    *tftypes.AttributePath
+	}{
+		"valid": {
+			config: map[string]tftypes.Value{
+				"endpoint": tftypes.NewValue(tftypes.String, server.URL),
+			},
+		},
+		"wrong token": {
+			config: map[string]tftypes.Value{
+				"endpoint": tftypes.NewValue(tftypes.String, server.URL),
+				"token":    tftypes.NewValue(tftypes.String, "wrong"),
+			},
+			expectedSummary: "Authentication Failed",
+			expectedPath:    tftypes.NewAttributePath().WithAttributeName("token"),
+		},
+		"unreachable": {
+			config: map[string]tftypes.Value{
+				"endpoint": tftypes.NewValue(tftypes.String, unreachable.URL),
+			},
+			expectedSummary: "Endpoint Unreachable",
+		},
+		"unreachable skipped": {
+			config: map[string]tftypes.Value{
+				"endpoint":                    tftypes.NewValue(tftypes.String, unreachable.URL),
+				"skip_credentials_validation": tftypes.NewValue(tftypes.Bool, true),
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, diags := testConfigureProvider(t, testCase.config)
+
+			if testCase.expectedSummary == "" {
+				if len(diags) > 0 {
+					t.Errorf("unexpected diagnostics: %v", diags)
+				}
+				return
+			}
+
+			if len(diags) != 1 {
+				t.Fatalf("expected 1 diagnostic, got %d: %v", len(diags), diags)
+			}
+			if diags[0].Severity != tfprotov6.DiagnosticSeverityError || diags[0].Summary != testCase.expectedSummary {
+				t.Errorf("expected error %q, got %s %q", testCase.expectedSummary, diags[0].Severity, diags[0].Summary)
+			}
+			if testCase.expectedPath != nil && !testCase.expectedPath.Equal(diags[0].Attribute) {
+				t.Errorf("expected error for %s, got %s", testCase.expectedPath, diags[0].Attribute)
+			}
+		})
+	}
+}
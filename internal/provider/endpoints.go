@@ -0,0 +1,216 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+// endpointFailover sends requests to the first endpoint which is reachable.
+// The client is built for the first endpoint, and requests are rewritten for
+// whichever endpoint they are sent to. Only idempotent requests are sent again
+// after an endpoint got them; others only go to the next endpoint if the
+// connection to the endpoint couldn't be made.
+type endpointFailover struct {
+	doer      runrs.HttpRequestDoer
+	base      string
+	endpoints []*url.URL
+
+	// preferred is the endpoint which served the last request, and is tried
+	// first by requests which are not pinned.
+	mu        sync.Mutex
+	preferred int
+}
+
+// newEndpointFailover returns an endpointFailover which sends requests through
+// doer to the given endpoints, in order.
+func newEndpointFailover(doer runrs.HttpRequestDoer, endpoints []string) (*endpointFailover, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no endpoints")
+	}
+
+	f := &endpointFailover{doer: doer}
+
+	for _, endpoint := range endpoints {
+		// Same as the runrs client, so that relative paths resolve below the
+		// endpoint.
+		if !strings.HasSuffix(endpoint, "/") {
+			endpoint += "/"
+		}
+
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+		}
+
+		f.endpoints = append(f.endpoints, u)
+	}
+
+	f.base = f.endpoints[0].String()
+
+	return f, nil
+}
+
+// endpointPinKey is the context key of the endpointPin.
+type endpointPinKey struct{}
+
+// endpointPin records the endpoint which served an operation, so that all of
+// its requests go to the same endpoint.
+type endpointPin struct {
+	mu       sync.Mutex
+	endpoint int
+	set      bool
+}
+
+// pinEndpoint returns a context whose requests all go to the endpoint which
+// serves the first of them, unless it becomes unreachable.
+func pinEndpoint(ctx context.Context) context.Context {
+	return context.WithValue(ctx, endpointPinKey{}, &endpointPin{})
+}
+
+func (f *endpointFailover) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	pin, _ := ctx.Value(endpointPinKey{}).(*endpointPin)
+
+	first := f.preferredEndpoint()
+	if pin != nil {
+		pin.mu.Lock()
+		if pin.set {
+			first = pin.endpoint
+		}
+		pin.mu.Unlock()
+	}
+
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead
+
+	var errs []error
+
+	for i := range f.endpoints {
+		index := (first + i) % len(f.endpoints)
+		endpoint := f.endpoints[index]
+
+		resp, err := f.do(req, endpoint, idempotent && i < len(f.endpoints)-1)
+		if err == nil {
+			tflog.Debug(ctx, "runrs request served", map[string]interface{}{
+				"endpoint": endpoint.String(),
+				"method":   req.Method,
+				"path":     req.URL.Path,
+				"status":   resp.StatusCode,
+			})
+
+			f.setPreferredEndpoint(index)
+			if pin != nil {
+				pin.mu.Lock()
+				pin.endpoint, pin.set = index, true
+				pin.mu.Unlock()
+			}
+
+			return resp, nil
+		}
+
+		// Don't try other endpoints once the operation has timed out or was
+		// cancelled.
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		tflog.Warn(ctx, "runrs endpoint failed", map[string]interface{}{
+			"endpoint": endpoint.String(),
+			"method":   req.Method,
+			"path":     req.URL.Path,
+			"error":    err.Error(),
+		})
+
+		// Creating a runner twice would leave one of them behind, so requests
+		// which may have been sent aren't sent again.
+		if len(f.endpoints) == 1 || (!idempotent && !isDialError(err)) {
+			return nil, err
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", endpoint, err))
+	}
+
+	return nil, fmt.Errorf("all endpoints failed: %w", errors.Join(errs...))
+}
+
+// do sends the request to the endpoint. Connection errors are returned as
+// errors, and so are responses with `ConnectionFailed` if the request may be
+// retried, so the next endpoint is tried. Other responses are returned as is.
+func (f *endpointFailover) do(req *http.Request, endpoint *url.URL, retry bool) (*http.Response, error) {
+	target, err := endpoint.Parse(strings.TrimPrefix(req.URL.String(), f.base))
+	if err != nil {
+		return nil, err
+	}
+
+	endpointReq := req.Clone(req.Context())
+	endpointReq.URL = target
+	endpointReq.Host = ""
+
+	if req.GetBody != nil {
+		endpointReq.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := f.doer.Do(endpointReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if !retry || resp.StatusCode < http.StatusInternalServerError {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var apiErr runrs.Error
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.ErrType == runrs.ConnectionFailed {
+		return nil, fmt.Errorf("%s: %s", apiErr.ErrType, apiErr.Msg)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	return resp, nil
+}
+
+// isDialError returns whether err is from connecting to an endpoint, before
+// any of the request was sent.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func (f *endpointFailover) preferredEndpoint() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.preferred
+}
+
+func (f *endpointFailover) setPreferredEndpoint(index int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.preferred = index
+}
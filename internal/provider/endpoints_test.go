@@ -0,0 +1,274 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+// testRunrsEndpoint is a runrs host which answers every request with the
+// given status and body, and counts the requests it got.
+type testRunrsEndpoint struct {
+	*httptest.Server
+
+	status   int
+	body     string
+	requests int
+}
+
+func newTestRunrsEndpoint(t *testing.T, status int, body string) *testRunrsEndpoint {
+	t.Helper()
+
+	e := &testRunrsEndpoint{status: status, body: body}
+	e.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(e.status)
+		_, _ = w.Write([]byte(e.body))
+	}))
+	t.Cleanup(e.Close)
+
+	return e
+}
+
+// testFailoverClient returns a runrs client which fails over between the
+// given endpoints.
+func testFailoverClient(t *testing.T, endpoints ...string) *runrs.ClientWithResponses {
+	t.Helper()
+
+	failover, err := newEndpointFailover(http.DefaultClient, endpoints)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := runrs.NewClientWithResponses(endpoints[0], runrs.WithHTTPClient(failover))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+func TestEndpointFailover(t *testing.T) {
+	const listBody = `{}`
+	const connectionFailedBody = `{"err_type": "ConnectionFailed", "msg": "docker is unreachable"}`
+	const notFoundBody = `{"err_type": "NotFound", "msg": "no runners"}`
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	testCases := map[string]struct {
+		status         int
+		body           string
+		firstURL       string
+		expectedStatus int
+		expectedFirst  int
+		expectedSecond int
+	}{
+		"first serves": {
+			status:         http.StatusOK,
+			body:           listBody,
+			expectedStatus: http.StatusOK,
+			expectedFirst:  1,
+		},
+		"connection error": {
+			firstURL:       unreachable.URL,
+			expectedStatus: http.StatusOK,
+			expectedSecond: 1,
+		},
+		"connection failed": {
+			status:         http.StatusInternalServerError,
+			body:           connectionFailedBody,
+			expectedStatus: http.StatusOK,
+			expectedFirst:  1,
+			expectedSecond: 1,
+		},
+		"other error": {
+			status:         http.StatusNotFound,
+			body:           notFoundBody,
+			expectedStatus: http.StatusNotFound,
+			expectedFirst:  1,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			first := newTestRunrsEndpoint(t, testCase.status, testCase.body)
+			second := newTestRunrsEndpoint(t, http.StatusOK, listBody)
+
+			firstURL := first.URL
+			if testCase.firstURL != "" {
+				firstURL = testCase.firstURL
+			}
+
+			client := testFailoverClient(t, firstURL, second.URL+"/")
+
+			resp, err := client.ListWithResponse(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode() != testCase.expectedStatus {
+				t.Errorf("expected status %d, got %d", testCase.expectedStatus, resp.StatusCode())
+			}
+			if first.requests != testCase.expectedFirst || second.requests != testCase.expectedSecond {
+				t.Errorf(
+					"expected %d and %d requests, got %d and %d",
+					testCase.expectedFirst,
+					testCase.expectedSecond,
+					first.requests,
+					second.requests,
+				)
+			}
+		})
+	}
+}
+
+func TestEndpointFailoverNonIdempotent(t *testing.T) {
+	const createdBody = `{"id": 42, "url": "https://gitlab.com/", "token": "glrt-0123456789-abcdefXYZ", "docker_image": "alpine:latest"}`
+	const connectionFailedBody = `{"err_type": "ConnectionFailed", "msg": "docker is unreachable"}`
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	testCases := map[string]struct {
+		status         int
+		body           string
+		firstURL       string
+		expectedStatus int
+		expectedFirst  int
+		expectedSecond int
+	}{
+		"connection error": {
+			firstURL:       unreachable.URL,
+			expectedStatus: http.StatusCreated,
+			expectedSecond: 1,
+		},
+		"connection failed": {
+			status:         http.StatusInternalServerError,
+			body:           connectionFailedBody,
+			expectedStatus: http.StatusInternalServerError,
+			expectedFirst:  1,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			first := newTestRunrsEndpoint(t, testCase.status, testCase.body)
+			second := newTestRunrsEndpoint(t, http.StatusCreated, createdBody)
+
+			firstURL := first.URL
+			if testCase.firstURL != "" {
+				firstURL = testCase.firstURL
+			}
+
+			client := testFailoverClient(t, firstURL, second.URL)
+
+			// The first endpoint may have created the runner before it failed,
+			// so it is only created at the second if the first never got the
+			// request.
+			resp, err := client.CreateWithResponse(context.Background(), runrs.GitLabRunner{
+				Id:          42,
+				Url:         "https://gitlab.com/",
+				Token:       "glrt-0123456789-abcdefXYZ",
+				DockerImage: "alpine:latest",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode() != testCase.expectedStatus {
+				t.Errorf("expected status %d, got %d", testCase.expectedStatus, resp.StatusCode())
+			}
+			if first.requests != testCase.expectedFirst || second.requests != testCase.expectedSecond {
+				t.Errorf(
+					"expected %d and %d requests, got %d and %d",
+					testCase.expectedFirst,
+					testCase.expectedSecond,
+					first.requests,
+					second.requests,
+				)
+			}
+		})
+	}
+}
+
+func TestEndpointFailoverAllFailed(t *testing.T) {
+	first := newTestRunrsEndpoint(t, http.StatusInternalServerError, `{"err_type": "ConnectionFailed", "msg": "first"}`)
+	second := newTestRunrsEndpoint(t, http.StatusInternalServerError, `{"err_type": "ConnectionFailed", "msg": "second"}`)
+
+	client := testFailoverClient(t, first.URL, second.URL)
+
+	// The last endpoint's error is returned as is.
+	resp, err := client.ListWithResponse(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiErr := resp.GetError(); apiErr == nil || apiErr.Msg != "second" {
+		t.Errorf("expected error of the second endpoint, got %v", apiErr)
+	}
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	client = testFailoverClient(t, unreachable.URL, unreachable.URL+"/")
+
+	_, err = client.ListWithResponse(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "all endpoints failed") {
+		t.Errorf("expected all endpoints to fail, got %v", err)
+	}
+}
+
+func TestEndpointFailoverPinned(t *testing.T) {
+	first := newTestRunrsEndpoint(t, http.StatusOK, `{}`)
+	second := newTestRunrsEndpoint(t, http.StatusOK, `{}`)
+
+	failover, err := newEndpointFailover(http.DefaultClient, []string{first.URL, second.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := runrs.NewClientWithResponses(first.URL, runrs.WithHTTPClient(failover))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An operation pinned to the second endpoint stays there, even once the
+	// first endpoint is reachable again.
+	first.status = http.StatusInternalServerError
+	first.body = `{"err_type": "ConnectionFailed", "msg": "docker is unreachable"}`
+
+	ctx := pinEndpoint(context.Background())
+	if _, err := client.ListWithResponse(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	first.status = http.StatusOK
+	first.body = `{}`
+
+	for range 2 {
+		if _, err := client.ListWithResponse(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if first.requests != 1 || second.requests != 3 {
+		t.Errorf("expected 1 and 3 requests, got %d and %d", first.requests, second.requests)
+	}
+
+	// Other operations go to the preferred endpoint.
+	failover.setPreferredEndpoint(0)
+	if _, err := client.ListWithResponse(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if first.requests != 2 {
+		t.Errorf("expected 2 requests to the first endpoint, got %d", first.requests)
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/boolvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/objectplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"terraform-provider-peripheral/internal/clients/gitlab"
+)
+
+// GitLabRunnerRegistrationModel describes the registration data model.
+type GitLabRunnerRegistrationModel struct {
+	ApiToken  types.String `tfsdk:"api_token"`
+	Instance  types.Bool   `tfsdk:"instance"`
+	GroupId   types.Int64  `tfsdk:"group_id"`
+	ProjectId types.Int64  `tfsdk:"project_id"`
+}
+
+// gitLabRunnerRegistrationAttribute returns the schema of the registration
+// attribute.
+func gitLabRunnerRegistrationAttribute() schema.SingleNestedAttribute {
+	scopes := []path.Expression{
+		path.MatchRelative().AtParent().AtName("instance"),
+		path.MatchRelative().AtParent().AtName("group_id"),
+		path.MatchRelative().AtParent().AtName("project_id"),
+	}
+
+	return schema.SingleNestedAttribute{
+		MarkdownDescription: "Register the GitLabRunner with GitLab on create, and remove it from " +
+			"GitLab on destroy. When set, `id` and `token` are computed instead of configured. " +
+			"Exactly one of `instance`, `group_id` or `project_id` must be set.",
+		Optional: true,
+		Attributes: map[string]schema.Attribute{
+			"api_token": schema.StringAttribute{
+				MarkdownDescription: "GitLab access token with the `create_runner` scope",
+				Required:            true,
+				Sensitive:           true,
+			},
+			"instance": schema.BoolAttribute{
+				MarkdownDescription: "Register an instance runner; must be `true` if set",
+				Optional:            true,
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.Bool{
+					boolvalidator.ExactlyOneOf(scopes...),
+				},
+			},
+			"group_id": schema.Int64Attribute{
+				MarkdownDescription: "ID of the GitLab group to register a group runner for",
+				Optional:            true,
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.RequiresReplace(),
+				},
+				Validators: []validator.Int64{
+					int64validator.ExactlyOneOf(scopes...),
+				},
+			},
+			"project_id": schema.Int64Attribute{
+				MarkdownDescription: "ID of the GitLab project to register a project runner for",
+				Optional:            true,
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.RequiresReplace(),
+				},
+				Validators: []validator.Int64{
+					int64validator.ExactlyOneOf(scopes...),
+				},
+			},
+		},
+		PlanModifiers: []planmodifier.Object{
+			objectplanmodifier.RequiresReplaceIf(
+				func(ctx context.Context, req planmodifier.ObjectRequest, resp *objectplanmodifier.RequiresReplaceIfFuncResponse) {
+					resp.RequiresReplace = req.StateValue.IsNull() != req.PlanValue.IsNull()
+				},
+				"Adding or removing registration requires replacing the GitLabRunner.",
+				"Adding or removing `registration` requires replacing the GitLabRunner.",
+			),
+		},
+	}
+}
+
+// validateRegistrationInstance ensures that registration.instance is not
+// set to false, which would leave the runner type ambiguous.
+func validateRegistrationInstance(ctx context.Context, config tfsdk.Config) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	instancePath := path.Root("registration").AtName("instance")
+
+	var instance types.Bool
+	diags.Append(config.GetAttribute(ctx, instancePath, &instance)...)
+	if diags.HasError() {
+		return diags
+	}
+
+	if !instance.IsNull() && !instance.IsUnknown() && !instance.ValueBool() {
+		diags.AddAttributeError(
+			instancePath,
+			"Invalid Attribute Value",
+			"`instance` must be `true` if set; use `group_id` or `project_id` to register "+
+				"group or project runners instead.",
+		)
+	}
+
+	return diags
+}
+
+// client returns a GitLab API client for the GitLab instance at url.
+func (m *GitLabRunnerRegistrationModel) client(url types.String) (*gitlab.Client, error) {
+	return gitlab.NewClient(url.ValueString(), m.ApiToken.ValueString())
+}
+
+// createRunnerOptions returns the options to register a runner with GitLab.
+func (m *GitLabRunnerRegistrationModel) createRunnerOptions(name types.String) gitlab.CreateRunnerOptions {
+	opts := gitlab.CreateRunnerOptions{
+		RunnerType:  gitlab.InstanceType,
+		Description: name.ValueStringPointer(),
+	}
+
+	switch {
+	case !m.GroupId.IsNull():
+		opts.RunnerType = gitlab.GroupType
+		opts.GroupId = m.GroupId.ValueInt64Pointer()
+	case !m.ProjectId.IsNull():
+		opts.RunnerType = gitlab.ProjectType
+		opts.ProjectId = m.ProjectId.ValueInt64Pointer()
+	}
+
+	return opts
+}
+
+// register creates the runner in GitLab and stores its ID and token in data.
+func (m *GitLabRunnerRegistrationModel) register(
+	ctx context.Context,
+	data *GitLabRunnerResourceModel,
+) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	client, err := m.client(data.Url)
+	if err != nil {
+		diags.AddError(
+			"GitLab Client Error",
+			fmt.Sprintf("Unable to set up GitLab client: %s", err),
+		)
+		return diags
+	}
+
+	runner, err := client.CreateRunner(ctx, m.createRunnerOptions(data.Name))
+	if err != nil {
+		diags.AddError(
+			"GitLab Client Error",
+			fmt.Sprintf("Unable to register GitLabRunner with GitLab: %s", err),
+		)
+		return diags
+	}
+
+	if runner.Id < 0 || runner.Id > math.MaxInt32 {
+		diags.AddError(
+			"GitLab Client Error",
+			fmt.Sprintf("GitLab returned runner ID %d, which is not a valid 32-bit integer", runner.Id),
+		)
+		diags.Append(m.unregister(ctx, data.Url, runner.Id)...)
+		return diags
+	}
+
+	data.Id = types.Int32Value(int32(runner.Id))
+	data.Token = types.StringValue(runner.Token)
+	data.TokenObtainedAt = types.StringValue(time.Now().UTC().Format(time.RFC3339))
+
+	tflog.Trace(ctx, fmt.Sprintf("registered GitLabRunner with ID %d in GitLab", runner.Id))
+
+	return diags
+}
+
+// unregister deletes the runner with the given ID from GitLab. A runner that
+// no longer exists in GitLab is not an error.
+func (m *GitLabRunnerRegistrationModel) unregister(
+	ctx context.Context,
+	url types.String,
+	id int64,
+) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	client, err := m.client(url)
+	if err != nil {
+		diags.AddError(
+			"GitLab Client Error",
+			fmt.Sprintf("Unable to set up GitLab client: %s", err),
+		)
+		return diags
+	}
+
+	if err := client.DeleteRunner(ctx, id); err != nil && !gitlab.IsNotFound(err) {
+		diags.AddError(
+			"GitLab Client Error",
+			fmt.Sprintf("Unable to remove GitLabRunner with ID %d from GitLab: %s", id, err),
+		)
+		return diags
+	}
+
+	tflog.Trace(ctx, fmt.Sprintf("removed GitLabRunner with ID %d from GitLab", id))
+
+	return diags
+}
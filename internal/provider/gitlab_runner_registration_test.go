@@ -0,0 +1,394 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+// testGitLabServer is a stand-in for the runner endpoints of the GitLab API.
+type testGitLabServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	nextId    int64
+	rotations int
+	runners   map[int64]string
+
+	// status is reported for all runners.
+	status string
+}
+
+func newTestGitLabServer(t *testing.T) *testGitLabServer {
+	t.Helper()
+
+	s := &testGitLabServer{
+		nextId:  1,
+		runners: map[int64]string{},
+		status:  "online",
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/user/runners", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("PRIVATE-TOKEN") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		id := s.nextId
+		s.nextId++
+		s.runners[id] = fmt.Sprintf("glrt-test%d_abcdefXYZ", id)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":    id,
+			"token": s.runners[id],
+		})
+	})
+	mux.HandleFunc("/api/v4/runners/reset_authentication_token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		var body struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		for id, token := range s.runners {
+			if token != body.Token {
+				continue
+			}
+
+			s.rotations++
+			s.runners[id] = fmt.Sprintf("glrt-test%d-%d_abcdefXYZ", id, s.rotations)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"token": s.runners[id],
+			})
+			return
+		}
+
+		w.WriteHeader(http.StatusForbidden)
+	})
+	mux.HandleFunc("/api/v4/runners/verify", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		var body struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		for id, token := range s.runners {
+			if token != body.Token {
+				continue
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id":    id,
+				"token": token,
+			})
+			return
+		}
+
+		w.WriteHeader(http.StatusForbidden)
+	})
+	mux.HandleFunc("/api/v4/runners/", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("PRIVATE-TOKEN"), "glpat-") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/v4/runners/"), 10, 64)
+		if err != nil || s.runners[id] == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id":     id,
+				"status": s.status,
+			})
+		case http.MethodDelete:
+			delete(s.runners, id)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// runnerCount returns the number of runners registered with the server.
+func (s *testGitLabServer) runnerCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.runners)
+}
+
+func testRunnerResourceRegistrationConfig(gitlabURL string) string {
+	return fmt.Sprintf(`
+		resource "%s" "%s" {
+		  name         = "%s"
+		  url          = "%s"
+		  docker_image = "alpine:latest"
+
+		  registration = {
+		    api_token = "glpat-0123456789abcdef"
+		    group_id  = 7
+		  }
+		}`,
+		resourceType,
+		resourceName,
+		initialRunnerName,
+		gitlabURL,
+	)
+}
+
+func TestAccRunnerResourceRegistration(t *testing.T) {
+	gitlab := newTestGitLabServer(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + testRunnerResourceRegistrationConfig(gitlab.URL),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						resourceCoordinate,
+						"id",
+						"1",
+					),
+					resource.TestCheckResourceAttr(
+						resourceCoordinate,
+						"token",
+						"glrt-test1_abcdefXYZ",
+					),
+					resource.TestCheckResourceAttrSet(
+						resourceCoordinate,
+						"token_obtained_at",
+					),
+				),
+			},
+		},
+		CheckDestroy: func(s *terraform.State) error {
+			if count := gitlab.runnerCount(); count != 0 {
+				return fmt.Errorf("expected no runners registered with GitLab, got %d", count)
+			}
+			return nil
+		},
+	})
+}
+
+func TestRunnerResourceValidateConfig(t *testing.T) {
+	ctx := context.Background()
+
+	server, err := providerserver.NewProtocol6WithError(New("test")())()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configType := testRunnerResourceSchemaState(t).Schema.Type().TerraformType(ctx).(tftypes.Object)
+	registrationType := configType.AttributeTypes["registration"].(tftypes.Object)
+	waitForReadyType := configType.AttributeTypes["wait_for_ready"].(tftypes.Object)
+
+	registration := func(attrs map[string]tftypes.Value) tftypes.Value {
+		values := map[string]tftypes.Value{}
+		for name, typ := range registrationType.AttributeTypes {
+			values[name] = tftypes.NewValue(typ, nil)
+		}
+		values["api_token"] = tftypes.NewValue(tftypes.String, "glpat-0123456789abcdef")
+		for name, value := range attrs {
+			values[name] = value
+		}
+		return tftypes.NewValue(registrationType, values)
+	}
+
+	testCases := map[string]struct {
+		attrs         map[string]tftypes.Value
+		expectedError string
+	}{
+		"id and token": {
+			attrs: map[string]tftypes.Value{
+				"id":    tftypes.NewValue(tftypes.Number, 42),
+				"token": tftypes.NewValue(tftypes.String, "glrt-0123456789_abcdefXYZ"),
+			},
+		},
+		"registration": {
+			attrs: map[string]tftypes.Value{
+				"registration": registration(map[string]tftypes.Value{
+					"instance": tftypes.NewValue(tftypes.Bool, true),
+				}),
+			},
+		},
+		"neither id nor registration": {
+			attrs: map[string]tftypes.Value{
+				"token": tftypes.NewValue(tftypes.String, "glrt-0123456789_abcdefXYZ"),
+			},
+			expectedError: "Missing Attribute Configuration",
+		},
+		"token_wo": {
+			attrs: map[string]tftypes.Value{
+				"token_wo":         tftypes.NewValue(tftypes.String, "glrt-0123456789_abcdefXYZ"),
+				"token_wo_version": tftypes.NewValue(tftypes.Number, 1),
+			},
+		},
+		"token and token_wo": {
+			attrs: map[string]tftypes.Value{
+				"id":       tftypes.NewValue(tftypes.Number, 42),
+				"token":    tftypes.NewValue(tftypes.String, "glrt-0123456789_abcdefXYZ"),
+				"token_wo": tftypes.NewValue(tftypes.String, "glrt-0123456789_abcdefXYZ"),
+			},
+			expectedError: "Invalid Attribute Combination",
+		},
+		"token_wo_version without token_wo": {
+			attrs: map[string]tftypes.Value{
+				"id":               tftypes.NewValue(tftypes.Number, 42),
+				"token":            tftypes.NewValue(tftypes.String, "glrt-0123456789_abcdefXYZ"),
+				"token_wo_version": tftypes.NewValue(tftypes.Number, 1),
+			},
+			expectedError: "Invalid Attribute Combination",
+		},
+		"wait_for_ready without api_token": {
+			attrs: map[string]tftypes.Value{
+				"id":    tftypes.NewValue(tftypes.Number, 42),
+				"token": tftypes.NewValue(tftypes.String, "glrt-0123456789_abcdefXYZ"),
+				"wait_for_ready": tftypes.NewValue(waitForReadyType, map[string]tftypes.Value{
+					"timeout":   tftypes.NewValue(tftypes.String, "1m"),
+					"api_token": tftypes.NewValue(tftypes.String, nil),
+				}),
+			},
+			expectedError: "Missing Attribute Configuration",
+		},
+		"id and registration": {
+			attrs: map[string]tftypes.Value{
+				"id": tftypes.NewValue(tftypes.Number, 42),
+				"registration": registration(map[string]tftypes.Value{
+					"instance": tftypes.NewValue(tftypes.Bool, true),
+				}),
+			},
+			expectedError: "Invalid Attribute Combination",
+		},
+		"registration without scope": {
+			attrs: map[string]tftypes.Value{
+				"registration": registration(nil),
+			},
+			expectedError: "Invalid Attribute Combination",
+		},
+		"registration with two scopes": {
+			attrs: map[string]tftypes.Value{
+				"registration": registration(map[string]tftypes.Value{
+					"group_id":   tftypes.NewValue(tftypes.Number, 7),
+					"project_id": tftypes.NewValue(tftypes.Number, 8),
+				}),
+			},
+			expectedError: "Invalid Attribute Combination",
+		},
+		"registration with instance false": {
+			attrs: map[string]tftypes.Value{
+				"registration": registration(map[string]tftypes.Value{
+					"instance": tftypes.NewValue(tftypes.Bool, false),
+				}),
+			},
+			expectedError: "Invalid Attribute Value",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			values := map[string]tftypes.Value{}
+			for name, typ := range configType.AttributeTypes {
+				values[name] = tftypes.NewValue(typ, nil)
+			}
+			values["url"] = tftypes.NewValue(tftypes.String, "https://gitlab.com/")
+			values["docker_image"] = tftypes.NewValue(tftypes.String, "alpine:latest")
+			for name, value := range testCase.attrs {
+				values[name] = value
+			}
+
+			config, err := tfprotov6.NewDynamicValue(configType, tftypes.NewValue(configType, values))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := server.ValidateResourceConfig(ctx, &tfprotov6.ValidateResourceConfigRequest{
+				TypeName: resourceType,
+				Config:   &config,
+				ClientCapabilities: &tfprotov6.ValidateResourceConfigClientCapabilities{
+					WriteOnlyAttributesAllowed: true,
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var summaries []string
+			for _, d := range resp.Diagnostics {
+				if d.Severity == tfprotov6.DiagnosticSeverityError {
+					summaries = append(summaries, d.Summary)
+				}
+			}
+
+			switch {
+			case testCase.expectedError == "" && len(summaries) > 0:
+				t.Errorf("unexpected errors: %v", summaries)
+			case testCase.expectedError != "" && !strings.Contains(strings.Join(summaries, "\n"), testCase.expectedError):
+				t.Errorf("expected error %q, got %v", testCase.expectedError, summaries)
+			}
+		})
+	}
+}
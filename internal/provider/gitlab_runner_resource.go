@@ -9,9 +9,16 @@ import (
 	"time"
 
 	uuidpkg "github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/resourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int32planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 
@@ -20,28 +27,35 @@ import (
 
 // GitLabRunnerResourceModel describes the resource data model.
 type GitLabRunnerResourceModel struct {
-	Uuid            types.String `tfsdk:"uuid"`
-	Id              types.Int32  `tfsdk:"id"`
-	Name            types.String `tfsdk:"name"`
-	Url             types.String `tfsdk:"url"`
-	Token           types.String `tfsdk:"token"`
-	TokenObtainedAt types.String `tfsdk:"token_obtained_at"`
-	DockerImage     types.String `tfsdk:"docker_image"`
+	Uuid            types.String                    `tfsdk:"uuid"`
+	Id              types.Int32                     `tfsdk:"id"`
+	Name            types.String                    `tfsdk:"name"`
+	Url             types.String                    `tfsdk:"url"`
+	Token           types.String                    `tfsdk:"token"`
+	TokenObtainedAt types.String                    `tfsdk:"token_obtained_at"`
+	TokenExpiresAt  types.String                    `tfsdk:"token_expires_at"`
+	TokenWo         types.String                    `tfsdk:"token_wo"`
+	TokenWoVersion  types.Int64                     `tfsdk:"token_wo_version"`
+	DockerImage     types.String                    `tfsdk:"docker_image"`
+	Registration    *GitLabRunnerRegistrationModel  `tfsdk:"registration"`
+	TokenRotation   *GitLabRunnerTokenRotationModel `tfsdk:"token_rotation"`
+	WaitForReady    *GitLabRunnerWaitForReadyModel  `tfsdk:"wait_for_ready"`
+	Ready           types.Bool                      `tfsdk:"ready"`
+	Timeouts        timeouts.Value                  `tfsdk:"timeouts"`
 }
 
-// FromGitLabRunner converts a GitLabRunner to a GitLabRunnerResourceModel.
-func FromGitLabRunner(runner *runrs.GitLabRunner) GitLabRunnerResourceModel {
+// FromGitLabRunner updates a GitLabRunnerResourceModel from a GitLabRunner.
+// Attributes which are not stored in runrs are left untouched.
+func (m *GitLabRunnerResourceModel) FromGitLabRunner(runner *runrs.GitLabRunner) {
 	ts, _ := runner.TokenObtainedAt.MarshalText()
 
-	return GitLabRunnerResourceModel{
-		Uuid:            types.StringValue(runner.Uuid.String()),
-		Id:              types.Int32Value(runner.Id),
-		Name:            types.StringValue(*runner.Name),
-		Url:             types.StringValue(runner.Url),
-		Token:           types.StringValue(runner.Token),
-		TokenObtainedAt: types.StringValue(string(ts)),
-		DockerImage:     types.StringValue(runner.DockerImage),
-	}
+	m.Uuid = types.StringValue(runner.Uuid.String())
+	m.Id = types.Int32Value(runner.Id)
+	m.Name = types.StringValue(*runner.Name)
+	m.Url = types.StringValue(runner.Url)
+	m.Token = types.StringValue(runner.Token)
+	m.TokenObtainedAt = types.StringValue(string(ts))
+	m.DockerImage = types.StringValue(runner.DockerImage)
 }
 
 // ToGitLabRunner converts a GitLabRunnerResourceModel to a GitLabRunner.
@@ -69,11 +83,37 @@ func (m *GitLabRunnerResourceModel) ToGitLabRunner() runrs.GitLabRunner {
 	}
 }
 
+// Default timeouts of the resource operations; create and update include
+// waiting for the runner if wait_for_ready is set.
+const (
+	defaultCreateTimeout = 20 * time.Minute
+	defaultReadTimeout   = 5 * time.Minute
+	defaultUpdateTimeout = 20 * time.Minute
+	defaultDeleteTimeout = 10 * time.Minute
+)
+
+// nullTimeouts returns the timeouts for state which is not built from a plan,
+// such as upgraded or moved state.
+func nullTimeouts() timeouts.Value {
+	return timeouts.Value{
+		Object: types.ObjectNull(map[string]attr.Type{
+			"create": types.StringType,
+			"read":   types.StringType,
+			"update": types.StringType,
+			"delete": types.StringType,
+		}),
+	}
+}
+
 // Ensure provider defined types fully satisfy framework interfaces.
 var (
-	_ resource.Resource                = &GitLabRunnerResource{}
-	_ resource.ResourceWithConfigure   = &GitLabRunnerResource{}
-	_ resource.ResourceWithImportState = &GitLabRunnerResource{}
+	_ resource.Resource                     = &GitLabRunnerResource{}
+	_ resource.ResourceWithConfigure        = &GitLabRunnerResource{}
+	_ resource.ResourceWithImportState      = &GitLabRunnerResource{}
+	_ resource.ResourceWithMoveState        = &GitLabRunnerResource{}
+	_ resource.ResourceWithConfigValidators = &GitLabRunnerResource{}
+	_ resource.ResourceWithValidateConfig   = &GitLabRunnerResource{}
+	_ resource.ResourceWithModifyPlan       = &GitLabRunnerResource{}
 )
 
 // NewGitLabRunnerResource creates a new GitLabRunnerResource.
@@ -83,7 +123,13 @@ func NewGitLabRunnerResource() resource.Resource {
 
 // GitLabRunnerResource defines the resource implementation.
 type GitLabRunnerResource struct {
-	client *runrs.ClientWithResponses
+	client             *runrs.ClientWithResponses
+	runners            *runnerCache
+	auditLog           *auditLog
+	tokenExpiry        time.Duration
+	tokenExpiryUnknown bool
+	tokenExpiryWarning time.Duration
+	server             *runrsInfo
 }
 
 func (r *GitLabRunnerResource) Metadata(
@@ -101,6 +147,7 @@ func (r *GitLabRunnerResource) Schema(
 ) {
 	resp.Schema = schema.Schema{
 		MarkdownDescription: "GitLabRunner resource",
+		Version:             gitLabRunnerSchemaVersion,
 
 		Attributes: map[string]schema.Attribute{
 			"uuid": schema.StringAttribute{
@@ -108,8 +155,13 @@ func (r *GitLabRunnerResource) Schema(
 				Computed:            true,
 			},
 			"id": schema.Int32Attribute{
-				MarkdownDescription: "GitLab Runner instance ID as provided by GitLab",
-				Required:            true,
+				MarkdownDescription: "GitLab Runner instance ID as provided by GitLab; " +
+					"required unless `token_wo` or `registration` is set",
+				Optional: true,
+				Computed: true,
+				PlanModifiers: []planmodifier.Int32{
+					int32planmodifier.UseStateForUnknown(),
+				},
 			},
 			"name": schema.StringAttribute{
 				MarkdownDescription: "Description of GitLabRunner",
@@ -120,21 +172,78 @@ func (r *GitLabRunnerResource) Schema(
 				Required:            true,
 			},
 			"token": schema.StringAttribute{
-				MarkdownDescription: "Token for GitLabRunner registration",
-				Required:            true,
+				MarkdownDescription: "Token for GitLabRunner registration; " +
+					"required unless `token_wo` or `registration` is set",
+				Optional: true,
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
 			},
 			"token_obtained_at": schema.StringAttribute{
 				MarkdownDescription: "Time when GitLabRunner token was obtained",
 				Computed:            true,
 			},
+			"token_expires_at": schema.StringAttribute{
+				MarkdownDescription: "Time when GitLabRunner token expires; only set if the provider " +
+					"is configured with `token_expiry`",
+				Computed: true,
+			},
 			"docker_image": schema.StringAttribute{
 				MarkdownDescription: "Docker image for GitLabRunner",
 				Required:            true,
 			},
+			"token_wo":         gitLabRunnerTokenWriteOnlyAttribute(),
+			"token_wo_version": gitLabRunnerTokenWriteOnlyVersionAttribute(),
+			"registration":     gitLabRunnerRegistrationAttribute(),
+			"token_rotation":   gitLabRunnerTokenRotationAttribute(),
+			"wait_for_ready":   gitLabRunnerWaitForReadyAttribute(),
+			"ready": schema.BoolAttribute{
+				MarkdownDescription: "Whether the runner had contacted GitLab by the end of the last " +
+					"apply; only set if `wait_for_ready` is set",
+				Computed: true,
+			},
 		},
+
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Read:   true,
+				Update: true,
+				Delete: true,
+			}),
+		},
+	}
+}
+
+func (r *GitLabRunnerResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{
+		resourcevalidator.Conflicting(
+			path.MatchRoot("id"),
+			path.MatchRoot("registration"),
+		),
+		resourcevalidator.AtLeastOneOf(
+			path.MatchRoot("id"),
+			path.MatchRoot("token_wo"),
+			path.MatchRoot("registration"),
+		),
+		resourcevalidator.ExactlyOneOf(
+			path.MatchRoot("token"),
+			path.MatchRoot("token_wo"),
+			path.MatchRoot("registration"),
+		),
 	}
 }
 
+func (r *GitLabRunnerResource) ValidateConfig(
+	ctx context.Context,
+	req resource.ValidateConfigRequest,
+	resp *resource.ValidateConfigResponse,
+) {
+	resp.Diagnostics.Append(validateRegistrationInstance(ctx, req.Config)...)
+	resp.Diagnostics.Append(validateWaitForReadyApiToken(ctx, req.Config)...)
+}
+
 func (r *GitLabRunnerResource) Configure(
 	ctx context.Context,
 	req resource.ConfigureRequest,
@@ -145,20 +254,119 @@ func (r *GitLabRunnerResource) Configure(
 		return
 	}
 
-	client, ok := req.ProviderData.(*runrs.ClientWithResponses)
+	providerData, ok := req.ProviderData.(*peripheralProviderData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Resource Configure Type",
 			fmt.Sprintf(
-				"Expected *runrs.Client, got: %T. Report this issue to the provider developers.",
+				"Expected *peripheralProviderData, got: %T. Report this issue to the provider developers.",
 				req.ProviderData,
 			),
 		)
 		return
 	}
 
-	r.client = client
+	r.client = providerData.client
+	r.runners = providerData.runners
+	r.auditLog = providerData.auditLog
+	r.tokenExpiry = providerData.tokenExpiry
+	r.tokenExpiryUnknown = providerData.tokenExpiryUnknown
+	r.tokenExpiryWarning = providerData.tokenExpiryWarning
+	r.server = providerData.server
+}
+
+func (r *GitLabRunnerResource) ModifyPlan(
+	ctx context.Context,
+	req resource.ModifyPlanRequest,
+	resp *resource.ModifyPlanResponse,
+) {
+	// Nothing to do when destroying the runner.
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	now := time.Now()
+
+	var plan, state GitLabRunnerResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.server.unsupportedRunnerAttributes(&plan)...)
+
+	// Nothing else to do when creating the runner.
+	if resp.Diagnostics.HasError() || req.State.Raw.IsNull() {
+		return
+	}
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	pending, diags := tokenPending(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// A token which the last apply failed to push to runrs is pushed by the
+	// next one.
+	if pending {
+		tflog.Debug(ctx, fmt.Sprintf("planning push of rotated token for GitLabRunner with ID %d", state.Id.ValueInt32()))
+
+		resp.Diagnostics.Append(
+			resp.Plan.SetAttribute(ctx, path.Root("token_obtained_at"), types.StringUnknown())...,
+		)
+	}
+
+	if plan.TokenRotation != nil {
+		rotate, diags := plan.TokenRotation.rotationDue(&state, now)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		if rotate {
+			tflog.Debug(ctx, fmt.Sprintf("planning token rotation for GitLabRunner with ID %d", state.Id.ValueInt32()))
+
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("token"), types.StringUnknown())...)
+			resp.Diagnostics.Append(
+				resp.Plan.SetAttribute(ctx, path.Root("token_obtained_at"), types.StringUnknown())...,
+			)
+			plan.Token = types.StringUnknown()
+		}
+	}
+
+	// A new token_wo_version means a new write-only token is sent to runrs,
+	// which may belong to another runner unless the ID is configured.
+	if !plan.TokenWoVersion.Equal(state.TokenWoVersion) {
+		resp.Diagnostics.Append(
+			resp.Plan.SetAttribute(ctx, path.Root("token_obtained_at"), types.StringUnknown())...,
+		)
+
+		var id types.Int32
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("id"), &id)...)
+		if id.IsNull() {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("id"), types.Int32Unknown())...)
+		}
+	}
+
+	// A runner which wasn't ready by the end of the last apply is waited for
+	// again, which is what tainting does for failed creates.
+	switch {
+	case plan.WaitForReady == nil:
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("ready"), types.BoolNull())...)
+	case !state.Ready.ValueBool():
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("ready"), types.BoolUnknown())...)
+	}
+
+	tokenExpiresAt, diags := r.planTokenExpiry(&plan, &state, now)
+	resp.Diagnostics.Append(diags...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("token_expires_at"), tokenExpiresAt)...)
 }
 
 func (r *GitLabRunnerResource) Create(
@@ -166,6 +374,17 @@ func (r *GitLabRunnerResource) Create(
 	req resource.CreateRequest,
 	resp *resource.CreateResponse,
 ) {
+	ctx, span := startSpan(ctx, "peripheral_gitlab_runner.Create")
+	defer func() { endSpan(span, resp.Diagnostics) }()
+	// Mismatches with the runrs spec are reported as warnings of the operation.
+	ctx = collectSpecMismatches(ctx)
+	defer func() { resp.Diagnostics.Append(specMismatchWarnings(ctx)...) }()
+
+	if r.client == nil {
+		resp.Diagnostics.Append(unknownConfigDiagnostic())
+		return
+	}
+
 	var data GitLabRunnerResourceModel
 
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -173,16 +392,56 @@ func (r *GitLabRunnerResource) Create(
 		return
 	}
 
-	apiResp, err := r.client.CreateWithResponse(ctx, data.ToGitLabRunner())
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Client Error",
-			fmt.Sprintf("Unable to talk to client, got error: %s", err),
-		)
+	timeout, diags := data.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	if err := apiResp.GetError(); err != nil {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Keep all requests of the operation on one endpoint, so that they see
+	// the same runrs state.
+	ctx = pinEndpoint(ctx)
+
+	tokenWo, diags := writeOnlyToken(ctx, req.Config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Registration != nil {
+		resp.Diagnostics.Append(data.Registration.register(ctx, &data)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	if !tokenWo.IsNull() && data.Id.IsUnknown() {
+		data.Id, diags = runnerIdForToken(ctx, data.Url, tokenWo)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	runner := data.ToGitLabRunner()
+	if !tokenWo.IsNull() {
+		runner.Token = tokenWo.ValueString()
+	}
+
+	apiResp, err := r.client.CreateWithResponse(withOperation(ctx, "create"), runner)
+
+	auditRunner := &runner
+	if err == nil && apiResp.JSON201 != nil {
+		auditRunner = apiResp.JSON201
+	}
+	resp.Diagnostics.Append(r.audit(auditCreate, auditRunner, auditChanges(nil, &runner), err, apiResp)...)
+
+	if err != nil {
+		resp.Diagnostics.Append(clientErrorDiagnostic(err))
+	} else if err := apiResp.GetError(); err != nil {
 		resp.Diagnostics.AddError(
 			"Client Error",
 			fmt.Sprintf(
@@ -191,10 +450,28 @@ func (r *GitLabRunnerResource) Create(
 				apiResp.Status(),
 			),
 		)
+	}
+
+	if resp.Diagnostics.HasError() {
+		// Don't leave a runner registered with GitLab that nothing manages.
+		if data.Registration != nil {
+			resp.Diagnostics.Append(
+				data.Registration.unregister(ctx, data.Url, int64(data.Id.ValueInt32()))...,
+			)
+		}
 		return
 	}
 
-	data = FromGitLabRunner(apiResp.JSON201)
+	data.FromGitLabRunner(apiResp.JSON201)
+	r.invalidateCached(data.Uuid.ValueString())
+	span.SetAttributes(runnerUuidAttribute.String(data.Uuid.ValueString()))
+	data.TokenExpiresAt = r.tokenExpiresAt(data.TokenObtainedAt)
+	if !tokenWo.IsNull() {
+		data.Token = types.StringNull()
+	}
+
+	// A runner which isn't ready is still saved, so that Terraform taints it.
+	resp.Diagnostics.Append(data.waitForReady(ctx)...)
 
 	// Write logs using the tflog package
 	// Documentation: https://terraform.io/plugin/log
@@ -209,6 +486,11 @@ func (r *GitLabRunnerResource) Read(
 	req resource.ReadRequest,
 	resp *resource.ReadResponse,
 ) {
+	ctx, span := startSpan(ctx, "peripheral_gitlab_runner.Read")
+	defer func() { endSpan(span, resp.Diagnostics) }()
+	ctx = collectSpecMismatches(ctx)
+	defer func() { resp.Diagnostics.Append(specMismatchWarnings(ctx)...) }()
+
 	var data GitLabRunnerResourceModel
 
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -216,28 +498,78 @@ func (r *GitLabRunnerResource) Read(
 		return
 	}
 
-	apiResp, err := r.client.ReadWithResponse(ctx, uuidpkg.MustParse(data.Uuid.ValueString()))
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Client Error",
-			fmt.Sprintf("Unable to talk to client, got error: %s", err),
-		)
+	timeout, diags := data.Timeouts.Read(ctx, defaultReadTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	if err := apiResp.GetError(); err != nil {
-		resp.Diagnostics.AddError(
-			"Client Error",
-			fmt.Sprintf(
-				"Unable to create GitLabRunner: %s (%s)",
-				err.Msg,
-				apiResp.Status(),
-			),
-		)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ctx = pinEndpoint(ctx)
+
+	span.SetAttributes(runnerUuidAttribute.String(data.Uuid.ValueString()))
+
+	// Runners moved from other resource types have no UUID until the next
+	// apply creates them in runrs, so there is nothing to read yet.
+	if data.Uuid.IsNull() {
+		tflog.Trace(ctx, fmt.Sprintf("skipped reading GitLabRunner with ID %d", data.Id.ValueInt32()))
+		return
+	}
+
+	// Without a known endpoint and token, runners are planned from their
+	// state until the provider is configured at apply time.
+	if r.client == nil {
+		tflog.Trace(ctx, fmt.Sprintf("skipped reading GitLabRunner with ID %d", data.Id.ValueInt32()))
+		return
+	}
+
+	runner, cached := r.readCached(ctx, data.Uuid.ValueString())
+	if !cached {
+		apiResp, err := r.client.ReadWithResponse(withOperation(ctx, "read"), uuidpkg.MustParse(data.Uuid.ValueString()))
+		if err != nil {
+			resp.Diagnostics.Append(clientErrorDiagnostic(err))
+			return
+		}
+
+		if err := apiResp.GetError(); err != nil {
+			resp.Diagnostics.AddError(
+				"Client Error",
+				fmt.Sprintf(
+					"Unable to create GitLabRunner: %s (%s)",
+					err.Msg,
+					apiResp.Status(),
+				),
+			)
+			return
+		}
+
+		runner = apiResp.JSON200
+	}
+
+	// Runners configured with token_wo have an ID but no token in state,
+	// whereas imported runners have neither until they have been read.
+	writeOnly := data.Token.IsNull() && !data.Id.IsNull()
+
+	pending, diags := tokenPending(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	data = FromGitLabRunner(apiResp.JSON200)
+	// runrs still has the token from before a rotation which the last apply
+	// failed to push, which is invalid in GitLab.
+	token, tokenObtainedAt := data.Token, data.TokenObtainedAt
+
+	data.FromGitLabRunner(runner)
+	if pending {
+		data.Token, data.TokenObtainedAt = token, tokenObtainedAt
+	}
+	data.TokenExpiresAt = r.tokenExpiresAt(data.TokenObtainedAt)
+	if writeOnly {
+		data.Token = types.StringNull()
+	}
 
 	// Write logs using the tflog package
 	// Documentation: https://terraform.io/plugin/log
@@ -252,6 +584,16 @@ func (r *GitLabRunnerResource) Update(
 	req resource.UpdateRequest,
 	resp *resource.UpdateResponse,
 ) {
+	ctx, span := startSpan(ctx, "peripheral_gitlab_runner.Update")
+	defer func() { endSpan(span, resp.Diagnostics) }()
+	ctx = collectSpecMismatches(ctx)
+	defer func() { resp.Diagnostics.Append(specMismatchWarnings(ctx)...) }()
+
+	if r.client == nil {
+		resp.Diagnostics.Append(unknownConfigDiagnostic())
+		return
+	}
+
 	var data GitLabRunnerResourceModel
 
 	// Read Terraform plan data into the model
@@ -260,24 +602,119 @@ func (r *GitLabRunnerResource) Update(
 		return
 	}
 
+	timeout, diags := data.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ctx = pinEndpoint(ctx)
+
 	// Read runner UUID from Terraform state
 	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("uuid"), &data.Uuid)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	runner := data.ToGitLabRunner()
+	span.SetAttributes(runnerUuidAttribute.String(data.Uuid.ValueString()))
+
+	// Runners moved from other resource types are created in runrs instead.
+	if data.Uuid.IsNull() {
+		createResp := resource.CreateResponse{
+			State:   resp.State,
+			Private: resp.Private,
+		}
+		r.Create(
+			ctx,
+			resource.CreateRequest{
+				Config:       req.Config,
+				Plan:         req.Plan,
+				ProviderMeta: req.ProviderMeta,
+			},
+			&createResp,
+		)
+		resp.State = createResp.State
+		resp.Diagnostics.Append(createResp.Diagnostics...)
+		return
+	}
 
-	apiResp, err := r.client.UpdateWithResponse(ctx, *runner.Uuid, runner)
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Client Error",
-			fmt.Sprintf("Unable to talk to client, got error: %s", err),
+	pending, diags := tokenPending(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// A token rotated by an apply which failed to push it to runrs is pushed
+	// now, with the time it was obtained.
+	if pending {
+		resp.Diagnostics.Append(
+			req.State.GetAttribute(ctx, path.Root("token_obtained_at"), &data.TokenObtainedAt)...,
 		)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	// A token unknown in the plan means ModifyPlan planned a token rotation.
+	rotated := false
+	if data.Token.IsUnknown() && data.Registration != nil {
+		var currentToken types.String
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("token"), &currentToken)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		resp.Diagnostics.Append(data.Registration.rotate(ctx, &data, currentToken)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		rotated = true
+	}
+
+	tokenWo, diags := writeOnlyToken(ctx, req.Config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	if err := apiResp.GetError(); err != nil {
+	if !tokenWo.IsNull() && data.Id.IsUnknown() {
+		data.Id, diags = runnerIdForToken(ctx, data.Url, tokenWo)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	runner := data.ToGitLabRunner()
+	if !tokenWo.IsNull() {
+		runner.Token = tokenWo.ValueString()
+	}
+
+	var state GitLabRunnerResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	priorRunner := state.ToGitLabRunner()
+	// Write-only tokens are sent on every update, but only change with
+	// token_wo_version.
+	if !tokenWo.IsNull() && state.Token.IsNull() && state.TokenWoVersion.Equal(data.TokenWoVersion) {
+		priorRunner.Token = runner.Token
+	}
+
+	r.invalidateCached(data.Uuid.ValueString())
+
+	apiResp, err := r.client.UpdateWithResponse(withOperation(ctx, "update"), *runner.Uuid, runner)
+
+	resp.Diagnostics.Append(r.audit(auditUpdate, &runner, auditChanges(&priorRunner, &runner), err, apiResp)...)
+
+	if err != nil {
+		resp.Diagnostics.Append(clientErrorDiagnostic(err))
+	} else if err := apiResp.GetError(); err != nil {
 		resp.Diagnostics.AddError(
 			"Client Error",
 			fmt.Sprintf(
@@ -286,18 +723,38 @@ func (r *GitLabRunnerResource) Update(
 				apiResp.Status(),
 			),
 		)
+	}
+
+	if resp.Diagnostics.HasError() {
+		// The old token is invalid once rotated, so keep the new one in
+		// state, along with the triggers it was rotated for, and mark it
+		// for the next apply to push to runrs instead of rotating again.
+		if rotated {
+			resp.State.Raw = req.State.Raw.Copy()
+			resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("token"), data.Token)...)
+			resp.Diagnostics.Append(
+				resp.State.SetAttribute(ctx, path.Root("token_obtained_at"), data.TokenObtainedAt)...,
+			)
+			resp.Diagnostics.Append(
+				resp.State.SetAttribute(ctx, path.Root("token_expires_at"), r.tokenExpiresAt(data.TokenObtainedAt))...,
+			)
+			resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("token_rotation"), data.TokenRotation)...)
+			resp.Diagnostics.Append(resp.Private.SetKey(ctx, pendingTokenKey, []byte("true"))...)
+		}
 		return
 	}
 
-	resp.Diagnostics.AddWarning(
-		"DEBUG PRINT",
-		fmt.Sprintf(
-			"%+v",
-			apiResp.JSON200,
-		),
-	)
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, pendingTokenKey, nil)...)
 
-	data = FromGitLabRunner(apiResp.JSON200)
+	data.FromGitLabRunner(apiResp.JSON200)
+	data.TokenExpiresAt = r.tokenExpiresAt(data.TokenObtainedAt)
+	if !tokenWo.IsNull() {
+		data.Token = types.StringNull()
+	}
+
+	// A runner which isn't ready is still saved, so that the next plan
+	// updates it again.
+	resp.Diagnostics.Append(data.waitForReady(ctx)...)
 
 	// Write logs using the tflog package
 	// Documentation: https://terraform.io/plugin/log
@@ -312,6 +769,11 @@ func (r *GitLabRunnerResource) Delete(
 	req resource.DeleteRequest,
 	resp *resource.DeleteResponse,
 ) {
+	ctx, span := startSpan(ctx, "peripheral_gitlab_runner.Delete")
+	defer func() { endSpan(span, resp.Diagnostics) }()
+	ctx = collectSpecMismatches(ctx)
+	defer func() { resp.Diagnostics.Append(specMismatchWarnings(ctx)...) }()
+
 	var data GitLabRunnerResourceModel
 
 	// Read Terraform prior state data into the model
@@ -320,12 +782,40 @@ func (r *GitLabRunnerResource) Delete(
 		return
 	}
 
-	apiResp, err := r.client.DeleteWithResponse(ctx, uuidpkg.MustParse(data.Uuid.ValueString()))
+	// Runners moved from other resource types have no UUID until the next
+	// apply creates them in runrs, so they are only removed from state.
+	if data.Uuid.IsNull() {
+		tflog.Trace(ctx, fmt.Sprintf("skipped deleting GitLabRunner with ID %d", data.Id.ValueInt32()))
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.Append(unknownConfigDiagnostic())
+		return
+	}
+
+	timeout, diags := data.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ctx = pinEndpoint(ctx)
+
+	span.SetAttributes(runnerUuidAttribute.String(data.Uuid.ValueString()))
+
+	r.invalidateCached(data.Uuid.ValueString())
+
+	apiResp, err := r.client.DeleteWithResponse(withOperation(ctx, "delete"), uuidpkg.MustParse(data.Uuid.ValueString()))
+
+	deletedRunner := data.ToGitLabRunner()
+	resp.Diagnostics.Append(r.audit(auditDelete, &deletedRunner, nil, err, apiResp)...)
+
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Client Error",
-			fmt.Sprintf("Unable to talk to client, got error: %s", err),
-		)
+		resp.Diagnostics.Append(clientErrorDiagnostic(err))
 		return
 	}
 
@@ -344,6 +834,50 @@ func (r *GitLabRunnerResource) Delete(
 	// Write logs using the tflog package
 	// Documentation: https://terraform.io/plugin/log
 	tflog.Trace(ctx, fmt.Sprintf("deleted GitLabRunner with UUID %s", data.Uuid.ValueString()))
+
+	if data.Registration != nil {
+		resp.Diagnostics.Append(
+			data.Registration.unregister(ctx, data.Url, int64(data.Id.ValueInt32()))...,
+		)
+	}
+}
+
+// audit records the operation in the audit log, if audit_log_path is set.
+func (r *GitLabRunnerResource) audit(
+	operation string,
+	runner *runrs.GitLabRunner,
+	changes map[string]auditChange,
+	err error,
+	apiResp interface{ GetError() *runrs.Error },
+) diag.Diagnostics {
+	if r.auditLog == nil {
+		return nil
+	}
+
+	var apiErr *runrs.Error
+	if err == nil {
+		apiErr = apiResp.GetError()
+	}
+
+	return r.auditLog.record(operation, runner, changes, err, apiErr)
+}
+
+// readCached returns the runner with the given UUID from the cache, or false
+// if batch_refresh is disabled or the runner has to be read from runrs.
+func (r *GitLabRunnerResource) readCached(ctx context.Context, uuid string) (*runrs.GitLabRunner, bool) {
+	if r.runners == nil {
+		return nil, false
+	}
+
+	return r.runners.get(ctx, uuid)
+}
+
+// invalidateCached stops serving the runner with the given UUID from the
+// cache, if batch_refresh is enabled.
+func (r *GitLabRunnerResource) invalidateCached(uuid string) {
+	if r.runners != nil {
+		r.runners.invalidate(uuid)
+	}
 }
 
 func (r *GitLabRunnerResource) ImportState(
@@ -0,0 +1,210 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// gitLabRunnerSchemaVersion is the current version of the resource schema.
+// Bump it whenever attributes of GitLabRunnerResourceModel are renamed or
+// change type, and implement UpgradeState to upgrade state from the previous
+// version. New attributes are null in existing state, and need no upgrade.
+const gitLabRunnerSchemaVersion = 0
+
+// peripheralRunnerRawState describes the state of the `peripheral_runner`
+// resource type documented by earlier releases of the provider as JSON. It
+// stored `description` and `image` in place of `name` and `docker_image`, so
+// both spellings are accepted.
+type peripheralRunnerRawState struct {
+	Uuid            *string      `json:"uuid"`
+	Id              *json.Number `json:"id"`
+	Name            *string      `json:"name"`
+	Description     *string      `json:"description"`
+	Url             *string      `json:"url"`
+	Token           *string      `json:"token"`
+	TokenObtainedAt *string      `json:"token_obtained_at"`
+	DockerImage     *string      `json:"docker_image"`
+	Image           *string      `json:"image"`
+}
+
+// toModel converts the `peripheral_runner` state to the resource data model.
+func (s *peripheralRunnerRawState) toModel() (GitLabRunnerResourceModel, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	id := types.Int32Null()
+	if s.Id != nil {
+		value, err := s.Id.Int64()
+		if err != nil || value < 0 || value > math.MaxInt32 {
+			diags.AddError(
+				"Invalid Source State",
+				fmt.Sprintf("GitLabRunner ID %q is not a valid 32-bit integer", s.Id.String()),
+			)
+			return GitLabRunnerResourceModel{}, diags
+		}
+		id = types.Int32Value(int32(value))
+	}
+
+	name := s.Name
+	if name == nil {
+		name = s.Description
+	}
+
+	dockerImage := s.DockerImage
+	if dockerImage == nil {
+		dockerImage = s.Image
+	}
+
+	return GitLabRunnerResourceModel{
+		Uuid:            types.StringPointerValue(s.Uuid),
+		Id:              id,
+		Name:            types.StringPointerValue(name),
+		Url:             types.StringPointerValue(s.Url),
+		Token:           types.StringPointerValue(s.Token),
+		TokenObtainedAt: types.StringPointerValue(s.TokenObtainedAt),
+		DockerImage:     types.StringPointerValue(dockerImage),
+		Timeouts:        nullTimeouts(),
+	}, diags
+}
+
+// gitLabUserRunnerRawState describes the parts of the `gitlab_user_runner`
+// state of the GitLab provider which carry over to this resource.
+type gitLabUserRunnerRawState struct {
+	Id          *string `json:"id"`
+	Token       *string `json:"token"`
+	Description *string `json:"description"`
+}
+
+// toModel converts the `gitlab_user_runner` state to the resource data model.
+// The runner does not exist in runrs yet, so the UUID is left null.
+func (s *gitLabUserRunnerRawState) toModel() (GitLabRunnerResourceModel, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	id := types.Int32Null()
+	if s.Id != nil {
+		value, err := strconv.ParseInt(*s.Id, 10, 32)
+		if err != nil || value < 0 {
+			diags.AddError(
+				"Invalid Source State",
+				fmt.Sprintf("GitLab runner ID %q is not a valid 32-bit integer", *s.Id),
+			)
+			return GitLabRunnerResourceModel{}, diags
+		}
+		id = types.Int32Value(int32(value))
+	}
+
+	return GitLabRunnerResourceModel{
+		Uuid:            types.StringNull(),
+		Id:              id,
+		Name:            types.StringPointerValue(s.Description),
+		Url:             types.StringNull(),
+		Token:           types.StringPointerValue(s.Token),
+		TokenObtainedAt: types.StringNull(),
+		DockerImage:     types.StringNull(),
+		Timeouts:        nullTimeouts(),
+	}, diags
+}
+
+func (r *GitLabRunnerResource) MoveState(ctx context.Context) []resource.StateMover {
+	return []resource.StateMover{
+		{
+			StateMover: moveGitLabRunnerStateFromLegacy,
+		},
+		{
+			StateMover: moveGitLabRunnerStateFromGitLabUserRunner,
+		},
+	}
+}
+
+// moveGitLabRunnerStateFromLegacy moves state from the `peripheral_runner`
+// resource type documented by earlier releases of the provider. Resources of
+// the same name from other providers are left alone.
+func moveGitLabRunnerStateFromLegacy(
+	ctx context.Context,
+	req resource.MoveStateRequest,
+	resp *resource.MoveStateResponse,
+) {
+	if req.SourceTypeName != "peripheral_runner" ||
+		!strings.HasSuffix(req.SourceProviderAddress, "bmc-labs/peripheral") {
+		return
+	}
+
+	if req.SourceRawState == nil || req.SourceRawState.JSON == nil {
+		resp.Diagnostics.AddError(
+			"Invalid Source State",
+			"Unable to move peripheral_runner state: source state is missing or not in JSON format",
+		)
+		return
+	}
+
+	var sourceState peripheralRunnerRawState
+	if err := json.Unmarshal(req.SourceRawState.JSON, &sourceState); err != nil {
+		resp.Diagnostics.AddError(
+			"Invalid Source State",
+			fmt.Sprintf("Unable to parse peripheral_runner state: %s", err),
+		)
+		return
+	}
+
+	data, diags := sourceState.toModel()
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Trace(ctx, fmt.Sprintf("moved peripheral_runner state with UUID %s", data.Uuid.ValueString()))
+
+	resp.Diagnostics.Append(resp.TargetState.Set(ctx, &data)...)
+}
+
+// moveGitLabRunnerStateFromGitLabUserRunner moves state from the
+// `gitlab_user_runner` resource type of the GitLab provider. The runner is
+// created in runrs by the next apply, without re-registering it in GitLab.
+func moveGitLabRunnerStateFromGitLabUserRunner(
+	ctx context.Context,
+	req resource.MoveStateRequest,
+	resp *resource.MoveStateResponse,
+) {
+	if req.SourceTypeName != "gitlab_user_runner" ||
+		!strings.HasSuffix(req.SourceProviderAddress, "gitlabhq/gitlab") {
+		return
+	}
+
+	if req.SourceRawState == nil || req.SourceRawState.JSON == nil {
+		resp.Diagnostics.AddError(
+			"Invalid Source State",
+			"Unable to move gitlab_user_runner state: source state is missing or not in JSON format",
+		)
+		return
+	}
+
+	var sourceState gitLabUserRunnerRawState
+	if err := json.Unmarshal(req.SourceRawState.JSON, &sourceState); err != nil {
+		resp.Diagnostics.AddError(
+			"Invalid Source State",
+			fmt.Sprintf("Unable to parse gitlab_user_runner state: %s", err),
+		)
+		return
+	}
+
+	data, diags := sourceState.toModel()
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Trace(ctx, fmt.Sprintf("moved gitlab_user_runner state with ID %d", data.Id.ValueInt32()))
+
+	resp.Diagnostics.Append(resp.TargetState.Set(ctx, &data)...)
+}
@@ -0,0 +1,213 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+	"terraform-provider-peripheral/internal/runrstest"
+)
+
+func testRunnerResourceSchemaState(t *testing.T) tfsdk.State {
+	t.Helper()
+
+	var schemaResp resource.SchemaResponse
+	NewGitLabRunnerResource().Schema(context.Background(), resource.SchemaRequest{}, &schemaResp)
+	if schemaResp.Diagnostics.HasError() {
+		t.Fatalf("unexpected schema diagnostics: %v", schemaResp.Diagnostics)
+	}
+
+	return tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(context.Background()), nil),
+	}
+}
+
+// testRunnerResourceModelEqual compares two models, where null timeouts are
+// equal regardless of their attribute types.
+func testRunnerResourceModelEqual(a, b GitLabRunnerResourceModel) bool {
+	if a.Timeouts.IsNull() && b.Timeouts.IsNull() {
+		a.Timeouts, b.Timeouts = timeouts.Value{}, timeouts.Value{}
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func testRunnerResourceMoveState(t *testing.T, req resource.MoveStateRequest) resource.MoveStateResponse {
+	t.Helper()
+
+	resp := resource.MoveStateResponse{
+		TargetState: testRunnerResourceSchemaState(t),
+	}
+
+	for _, mover := range NewGitLabRunnerResource().(*GitLabRunnerResource).MoveState(context.Background()) {
+		mover.StateMover(context.Background(), req, &resp)
+		if resp.Diagnostics.HasError() || !resp.TargetState.Raw.IsNull() {
+			break
+		}
+	}
+
+	return resp
+}
+
+func TestRunnerResourceMoveStateFromLegacy(t *testing.T) {
+	resp := testRunnerResourceMoveState(t, resource.MoveStateRequest{
+		SourceProviderAddress: "registry.terraform.io/bmc-labs/peripheral",
+		SourceTypeName:        "peripheral_runner",
+		SourceRawState: &tfprotov6.RawState{JSON: []byte(`{
+			"uuid": "be924fdd-fb28-468c-8c70-1f0ed3af4485",
+			"id": 42,
+			"description": "my-runner",
+			"url": "https://gitlab.com/",
+			"token": "glrt-0123456789-abcdefXYZ",
+			"image": "alpine:latest",
+			"tag_list": "tag1,tag2",
+			"run_untagged": false
+		}`)},
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+	}
+
+	var actual GitLabRunnerResourceModel
+	if diags := resp.TargetState.Get(context.Background(), &actual); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	expected := GitLabRunnerResourceModel{
+		Uuid:            types.StringValue("be924fdd-fb28-468c-8c70-1f0ed3af4485"),
+		Id:              types.Int32Value(42),
+		Name:            types.StringValue("my-runner"),
+		Url:             types.StringValue("https://gitlab.com/"),
+		Token:           types.StringValue("glrt-0123456789-abcdefXYZ"),
+		TokenObtainedAt: types.StringNull(),
+		DockerImage:     types.StringValue("alpine:latest"),
+	}
+
+	if !testRunnerResourceModelEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestRunnerResourceMoveStateFromLegacyInvalidId(t *testing.T) {
+	resp := testRunnerResourceMoveState(t, resource.MoveStateRequest{
+		SourceProviderAddress: "registry.terraform.io/bmc-labs/peripheral",
+		SourceTypeName:        "peripheral_runner",
+		SourceRawState:        &tfprotov6.RawState{JSON: []byte(`{"id": 4294967296}`)},
+	})
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected error diagnostic for out-of-range ID")
+	}
+}
+
+func TestRunnerResourceMoveStateFromGitLabUserRunner(t *testing.T) {
+	resp := testRunnerResourceMoveState(t, resource.MoveStateRequest{
+		SourceProviderAddress: "registry.terraform.io/gitlabhq/gitlab",
+		SourceTypeName:        "gitlab_user_runner",
+		SourceRawState: &tfprotov6.RawState{JSON: []byte(`{
+			"id": "42",
+			"token": "glrt-0123456789-abcdefXYZ",
+			"description": "my-runner",
+			"runner_type": "instance_type",
+			"tag_list": ["tag1", "tag2"],
+			"untagged": false
+		}`)},
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+	}
+
+	var actual GitLabRunnerResourceModel
+	if diags := resp.TargetState.Get(context.Background(), &actual); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	expected := GitLabRunnerResourceModel{
+		Uuid:            types.StringNull(),
+		Id:              types.Int32Value(42),
+		Name:            types.StringValue("my-runner"),
+		Url:             types.StringNull(),
+		Token:           types.StringValue("glrt-0123456789-abcdefXYZ"),
+		TokenObtainedAt: types.StringNull(),
+		DockerImage:     types.StringNull(),
+	}
+
+	if !testRunnerResourceModelEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestRunnerResourceMoveStateFromGitLabUserRunnerDestroy(t *testing.T) {
+	moved := testRunnerResourceMoveState(t, resource.MoveStateRequest{
+		SourceProviderAddress: "registry.terraform.io/gitlabhq/gitlab",
+		SourceTypeName:        "gitlab_user_runner",
+		SourceRawState: &tfprotov6.RawState{JSON: []byte(`{
+			"id": "42",
+			"token": "glrt-0123456789-abcdefXYZ",
+			"description": "my-runner"
+		}`)},
+	})
+	if moved.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", moved.Diagnostics)
+	}
+
+	server := runrstest.NewServer(testRunrsSecret)
+	t.Cleanup(server.Close)
+
+	client, err := runrs.NewClientWithResponses(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Destroying before the next apply created the runner in runrs must not
+	// talk to runrs, as there is no UUID to delete.
+	r := &GitLabRunnerResource{client: client}
+	resp := resource.DeleteResponse{State: moved.TargetState}
+	r.Delete(context.Background(), resource.DeleteRequest{State: moved.TargetState}, &resp)
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+	}
+	if len(server.Runners()) != 0 {
+		t.Errorf("expected no runners in runrs, got %v", server.Runners())
+	}
+}
+
+func TestRunnerResourceMoveStateUnsupportedSource(t *testing.T) {
+	testCases := map[string]resource.MoveStateRequest{
+		"other resource type": {
+			SourceProviderAddress: "registry.terraform.io/hashicorp/random",
+			SourceTypeName:        "random_string",
+			SourceRawState:        &tfprotov6.RawState{JSON: []byte(`{"id": "abc"}`)},
+		},
+		"peripheral_runner of another provider": {
+			SourceProviderAddress: "registry.terraform.io/example/peripheral",
+			SourceTypeName:        "peripheral_runner",
+			SourceRawState:        &tfprotov6.RawState{JSON: []byte(`{"uuid": "abc", "id": "42"}`)},
+		},
+	}
+
+	for name, req := range testCases {
+		t.Run(name, func(t *testing.T) {
+			resp := testRunnerResourceMoveState(t, req)
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+			}
+
+			if !resp.TargetState.Raw.IsNull() {
+				t.Errorf("expected target state to be left unset, got %s", resp.TargetState.Raw)
+			}
+		})
+	}
+}
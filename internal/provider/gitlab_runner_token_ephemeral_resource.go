@@ -0,0 +1,357 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	uuidpkg "github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework-validators/boolvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/ephemeralvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/objectvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+// GitLabRunnerTokenEphemeralResourceModel describes the ephemeral resource
+// data model.
+type GitLabRunnerTokenEphemeralResourceModel struct {
+	Uuid            types.String                   `tfsdk:"uuid"`
+	Name            types.String                   `tfsdk:"name"`
+	Url             types.String                   `tfsdk:"url"`
+	Registration    *GitLabRunnerRegistrationModel `tfsdk:"registration"`
+	Id              types.Int32                    `tfsdk:"id"`
+	Token           types.String                   `tfsdk:"token"`
+	TokenObtainedAt types.String                   `tfsdk:"token_obtained_at"`
+}
+
+// gitLabRunnerTokenPrivate is kept between Open and Close for registered
+// runners, which are removed from GitLab on Close.
+type gitLabRunnerTokenPrivate struct {
+	Url      string `json:"url"`
+	ApiToken string `json:"api_token"`
+	Id       int64  `json:"id"`
+}
+
+// gitLabRunnerTokenPrivateKey is the private data key for
+// gitLabRunnerTokenPrivate.
+const gitLabRunnerTokenPrivateKey = "registration"
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var (
+	_ ephemeral.EphemeralResource                     = &GitLabRunnerTokenEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithConfigure        = &GitLabRunnerTokenEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithConfigValidators = &GitLabRunnerTokenEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithValidateConfig   = &GitLabRunnerTokenEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithClose            = &GitLabRunnerTokenEphemeralResource{}
+)
+
+// NewGitLabRunnerTokenEphemeralResource creates a new
+// GitLabRunnerTokenEphemeralResource.
+func NewGitLabRunnerTokenEphemeralResource() ephemeral.EphemeralResource {
+	return &GitLabRunnerTokenEphemeralResource{}
+}
+
+// GitLabRunnerTokenEphemeralResource defines the ephemeral resource
+// implementation.
+type GitLabRunnerTokenEphemeralResource struct {
+	client *runrs.ClientWithResponses
+}
+
+func (r *GitLabRunnerTokenEphemeralResource) Metadata(
+	ctx context.Context,
+	req ephemeral.MetadataRequest,
+	resp *ephemeral.MetadataResponse,
+) {
+	resp.TypeName = req.ProviderTypeName + "_gitlab_runner_token"
+}
+
+func (r *GitLabRunnerTokenEphemeralResource) Schema(
+	ctx context.Context,
+	req ephemeral.SchemaRequest,
+	resp *ephemeral.SchemaResponse,
+) {
+	scopes := []path.Expression{
+		path.MatchRelative().AtParent().AtName("instance"),
+		path.MatchRelative().AtParent().AtName("group_id"),
+		path.MatchRelative().AtParent().AtName("project_id"),
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "GitLabRunner token, which is never stored in the plan or state. Either " +
+			"fetches the token of an existing GitLabRunner from runrs, or registers a new runner with " +
+			"GitLab. Registered runners are removed from GitLab again when Terraform is done with the " +
+			"token, so their token only lasts for the run; to pass a token to `token_wo` of " +
+			"`peripheral_gitlab_runner`, fetch it by `uuid` instead.",
+
+		Attributes: map[string]schema.Attribute{
+			"uuid": schema.StringAttribute{
+				MarkdownDescription: "UUID of the GitLabRunner in runrs to fetch the token of",
+				Optional:            true,
+			},
+			"name": schema.StringAttribute{
+				MarkdownDescription: "Description of GitLabRunner",
+				Optional:            true,
+				Computed:            true,
+			},
+			"url": schema.StringAttribute{
+				MarkdownDescription: "URL of GitLab instance for GitLabRunner; required if " +
+					"`registration` is set",
+				Optional: true,
+				Computed: true,
+			},
+			"registration": schema.SingleNestedAttribute{
+				MarkdownDescription: "Register a new runner with GitLab to obtain a token. Exactly one " +
+					"of `instance`, `group_id` or `project_id` must be set.",
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"api_token": schema.StringAttribute{
+						MarkdownDescription: "GitLab access token with the `create_runner` scope",
+						Required:            true,
+						Sensitive:           true,
+					},
+					"instance": schema.BoolAttribute{
+						MarkdownDescription: "Register an instance runner; must be `true` if set",
+						Optional:            true,
+						Validators: []validator.Bool{
+							boolvalidator.ExactlyOneOf(scopes...),
+						},
+					},
+					"group_id": schema.Int64Attribute{
+						MarkdownDescription: "ID of the GitLab group to register a group runner for",
+						Optional:            true,
+						Validators: []validator.Int64{
+							int64validator.ExactlyOneOf(scopes...),
+						},
+					},
+					"project_id": schema.Int64Attribute{
+						MarkdownDescription: "ID of the GitLab project to register a project runner for",
+						Optional:            true,
+						Validators: []validator.Int64{
+							int64validator.ExactlyOneOf(scopes...),
+						},
+					},
+				},
+				Validators: []validator.Object{
+					objectvalidator.AlsoRequires(path.MatchRoot("url")),
+				},
+			},
+			"id": schema.Int32Attribute{
+				MarkdownDescription: "GitLab Runner instance ID as provided by GitLab",
+				Computed:            true,
+			},
+			"token": schema.StringAttribute{
+				MarkdownDescription: "Token of GitLabRunner",
+				Computed:            true,
+				Sensitive:           true,
+			},
+			"token_obtained_at": schema.StringAttribute{
+				MarkdownDescription: "Time when GitLabRunner token was obtained",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (r *GitLabRunnerTokenEphemeralResource) ConfigValidators(
+	ctx context.Context,
+) []ephemeral.ConfigValidator {
+	return []ephemeral.ConfigValidator{
+		ephemeralvalidator.ExactlyOneOf(
+			path.MatchRoot("uuid"),
+			path.MatchRoot("registration"),
+		),
+	}
+}
+
+func (r *GitLabRunnerTokenEphemeralResource) ValidateConfig(
+	ctx context.Context,
+	req ephemeral.ValidateConfigRequest,
+	resp *ephemeral.ValidateConfigResponse,
+) {
+	resp.Diagnostics.Append(validateRegistrationInstance(ctx, req.Config)...)
+}
+
+func (r *GitLabRunnerTokenEphemeralResource) Configure(
+	ctx context.Context,
+	req ephemeral.ConfigureRequest,
+	resp *ephemeral.ConfigureResponse,
+) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*peripheralProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Ephemeral Resource Configure Type",
+			fmt.Sprintf(
+				"Expected *peripheralProviderData, got: %T. Report this issue to the provider developers.",
+				req.ProviderData,
+			),
+		)
+		return
+	}
+
+	r.client = providerData.client
+}
+
+func (r *GitLabRunnerTokenEphemeralResource) Open(
+	ctx context.Context,
+	req ephemeral.OpenRequest,
+	resp *ephemeral.OpenResponse,
+) {
+	var data GitLabRunnerTokenEphemeralResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Registration != nil {
+		r.openRegistration(ctx, &data, resp)
+	} else {
+		r.openRunner(ctx, &data, resp)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+}
+
+// openRunner fetches the token of an existing runner from runrs.
+func (r *GitLabRunnerTokenEphemeralResource) openRunner(
+	ctx context.Context,
+	data *GitLabRunnerTokenEphemeralResourceModel,
+	resp *ephemeral.OpenResponse,
+) {
+	uuid, err := uuidpkg.Parse(data.Uuid.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("uuid"),
+			"Invalid UUID",
+			fmt.Sprintf("Unable to parse uuid: %s", err),
+		)
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.Append(unknownConfigDiagnostic())
+		return
+	}
+
+	ctx = collectSpecMismatches(ctx)
+	apiResp, err := r.client.ReadWithResponse(withOperation(ctx, "read"), uuid)
+	resp.Diagnostics.Append(specMismatchWarnings(ctx)...)
+	if err != nil {
+		resp.Diagnostics.Append(clientErrorDiagnostic(err))
+		return
+	}
+
+	if err := apiResp.GetError(); err != nil {
+		resp.Diagnostics.AddError(
+			"Client Error",
+			fmt.Sprintf(
+				"Unable to read GitLabRunner: %s (%s)",
+				err.Msg,
+				apiResp.Status(),
+			),
+		)
+		return
+	}
+
+	var runner GitLabRunnerResourceModel
+	runner.FromGitLabRunner(apiResp.JSON200)
+
+	data.Name = runner.Name
+	data.Url = runner.Url
+	data.Id = runner.Id
+	data.Token = runner.Token
+	data.TokenObtainedAt = runner.TokenObtainedAt
+
+	tflog.Trace(ctx, fmt.Sprintf("fetched token of GitLabRunner with UUID %s", data.Uuid.ValueString()))
+}
+
+// openRegistration registers a new runner with GitLab, and remembers it in
+// the private data for Close to remove it again.
+func (r *GitLabRunnerTokenEphemeralResource) openRegistration(
+	ctx context.Context,
+	data *GitLabRunnerTokenEphemeralResourceModel,
+	resp *ephemeral.OpenResponse,
+) {
+	runner := GitLabRunnerResourceModel{
+		Name: data.Name,
+		Url:  data.Url,
+	}
+
+	resp.Diagnostics.Append(data.Registration.register(ctx, &runner)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.Id = runner.Id
+	data.Token = runner.Token
+	data.TokenObtainedAt = runner.TokenObtainedAt
+
+	private, err := json.Marshal(gitLabRunnerTokenPrivate{
+		Url:      data.Url.ValueString(),
+		ApiToken: data.Registration.ApiToken.ValueString(),
+		Id:       int64(data.Id.ValueInt32()),
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Private Data Error",
+			fmt.Sprintf("Unable to encode private data: %s", err),
+		)
+	} else {
+		resp.Diagnostics.Append(resp.Private.SetKey(ctx, gitLabRunnerTokenPrivateKey, private)...)
+	}
+
+	// Close is only called on success, so don't leave the runner behind.
+	if resp.Diagnostics.HasError() {
+		resp.Diagnostics.Append(
+			data.Registration.unregister(ctx, data.Url, int64(data.Id.ValueInt32()))...,
+		)
+	}
+}
+
+func (r *GitLabRunnerTokenEphemeralResource) Close(
+	ctx context.Context,
+	req ephemeral.CloseRequest,
+	resp *ephemeral.CloseResponse,
+) {
+	private, diags := req.Private.GetKey(ctx, gitLabRunnerTokenPrivateKey)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() || private == nil {
+		return
+	}
+
+	var data gitLabRunnerTokenPrivate
+	if err := json.Unmarshal(private, &data); err != nil {
+		resp.Diagnostics.AddError(
+			"Private Data Error",
+			fmt.Sprintf("Unable to decode private data: %s", err),
+		)
+		return
+	}
+
+	registration := GitLabRunnerRegistrationModel{
+		ApiToken: types.StringValue(data.ApiToken),
+	}
+	resp.Diagnostics.Append(
+		registration.unregister(ctx, types.StringValue(data.Url), data.Id)...,
+	)
+}
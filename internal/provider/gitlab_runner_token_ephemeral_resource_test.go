@@ -0,0 +1,293 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+	"terraform-provider-peripheral/internal/runrstest"
+)
+
+func TestRunnerTokenEphemeralResource(t *testing.T) {
+	ctx := context.Background()
+
+	var schemaResp ephemeral.SchemaResponse
+	NewGitLabRunnerTokenEphemeralResource().Schema(ctx, ephemeral.SchemaRequest{}, &schemaResp)
+	configType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	runrsServer := runrstest.NewServer(testRunrsSecret)
+	t.Cleanup(runrsServer.Close)
+
+	auth := newRunrsAuth(staticCredential(testRunrsSecret), jwt.MapClaims{})
+	client, err := runrs.NewClientWithResponses(runrsServer.URL, runrs.WithRequestEditorFn(auth.Intercept))
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := client.CreateWithResponse(ctx, runrs.GitLabRunner{
+		Id:          42,
+		Url:         "https://gitlab.com/",
+		Token:       "glrt-0123456789-abcdefXYZ",
+		DockerImage: "alpine:latest",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.JSON201 == nil {
+		t.Fatalf("unexpected create response %d: %s", created.StatusCode(), created.Body)
+	}
+
+	server, diags := testConfigureProvider(t, map[string]tftypes.Value{
+		"endpoint": tftypes.NewValue(tftypes.String, runrsServer.URL),
+	})
+	for _, d := range diags {
+		if d.Severity == tfprotov6.DiagnosticSeverityError {
+			t.Fatalf("unable to configure provider: %s: %s", d.Summary, d.Detail)
+		}
+	}
+
+	testCases := map[string]struct {
+		uuid            string
+		expectedToken   string
+		expectedSummary string
+	}{
+		"existing runner": {
+			uuid:          created.JSON201.Uuid.String(),
+			expectedToken: "glrt-0123456789-abcdefXYZ",
+		},
+		"unknown runner": {
+			uuid:            "be924fdd-fb28-468c-8c70-1f0ed3af4485",
+			expectedSummary: "Client Error",
+		},
+		"invalid uuid": {
+			uuid:            "not-a-uuid",
+			expectedSummary: "Invalid UUID",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			values := map[string]tftypes.Value{}
+			for name, typ := range configType.AttributeTypes {
+				values[name] = tftypes.NewValue(typ, nil)
+			}
+			values["uuid"] = tftypes.NewValue(tftypes.String, testCase.uuid)
+
+			config, err := tfprotov6.NewDynamicValue(configType, tftypes.NewValue(configType, values))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			openResp, err := server.OpenEphemeralResource(ctx, &tfprotov6.OpenEphemeralResourceRequest{
+				TypeName: "peripheral_gitlab_runner_token",
+				Config:   &config,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if testCase.expectedSummary != "" {
+				if len(openResp.Diagnostics) != 1 || openResp.Diagnostics[0].Summary != testCase.expectedSummary {
+					t.Errorf("expected %q, got %v", testCase.expectedSummary, openResp.Diagnostics)
+				}
+				return
+			}
+			for _, d := range openResp.Diagnostics {
+				t.Errorf("unexpected diagnostic: %s: %s", d.Summary, d.Detail)
+			}
+
+			result, err := openResp.Result.Unmarshal(configType)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var attrs map[string]tftypes.Value
+			if err := result.As(&attrs); err != nil {
+				t.Fatal(err)
+			}
+
+			var token string
+			if err := attrs["token"].As(&token); err != nil {
+				t.Fatal(err)
+			}
+			if token != testCase.expectedToken {
+				t.Errorf("unexpected token: %q", token)
+			}
+
+			// Nothing is registered on open, so there is nothing to close.
+			if openResp.Private != nil {
+				t.Errorf("expected no private data, got %v", openResp.Private)
+			}
+		})
+	}
+}
+
+func TestRunnerTokenEphemeralResourceRegistration(t *testing.T) {
+	ctx := context.Background()
+
+	var schemaResp ephemeral.SchemaResponse
+	NewGitLabRunnerTokenEphemeralResource().Schema(ctx, ephemeral.SchemaRequest{}, &schemaResp)
+	configType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+	registrationType := configType.AttributeTypes["registration"].(tftypes.Object)
+
+	gitlab := newTestGitLabServer(t)
+	server := testProtocol6ProviderServer(t)
+
+	values := map[string]tftypes.Value{}
+	for name, typ := range configType.AttributeTypes {
+		values[name] = tftypes.NewValue(typ, nil)
+	}
+	values["url"] = tftypes.NewValue(tftypes.String, gitlab.URL)
+	values["registration"] = tftypes.NewValue(registrationType, map[string]tftypes.Value{
+		"api_token":  tftypes.NewValue(tftypes.String, "glpat-0123456789abcdef"),
+		"instance":   tftypes.NewValue(tftypes.Bool, nil),
+		"group_id":   tftypes.NewValue(tftypes.Number, 7),
+		"project_id": tftypes.NewValue(tftypes.Number, nil),
+	})
+
+	config, err := tfprotov6.NewDynamicValue(configType, tftypes.NewValue(configType, values))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	openResp, err := server.OpenEphemeralResource(ctx, &tfprotov6.OpenEphemeralResourceRequest{
+		TypeName: "peripheral_gitlab_runner_token",
+		Config:   &config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range openResp.Diagnostics {
+		t.Errorf("unexpected diagnostic: %s: %s", d.Summary, d.Detail)
+	}
+
+	result, err := openResp.Result.Unmarshal(configType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var attrs map[string]tftypes.Value
+	if err := result.As(&attrs); err != nil {
+		t.Fatal(err)
+	}
+
+	var token string
+	if err := attrs["token"].As(&token); err != nil {
+		t.Fatal(err)
+	}
+	if token != "glrt-test1_abcdefXYZ" {
+		t.Errorf("unexpected token: %q", token)
+	}
+	if count := gitlab.runnerCount(); count != 1 {
+		t.Errorf("expected one runner registered with GitLab after open, got %d", count)
+	}
+
+	closeResp, err := server.CloseEphemeralResource(ctx, &tfprotov6.CloseEphemeralResourceRequest{
+		TypeName: "peripheral_gitlab_runner_token",
+		Private:  openResp.Private,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range closeResp.Diagnostics {
+		t.Errorf("unexpected diagnostic: %s: %s", d.Summary, d.Detail)
+	}
+
+	// The registered runner only lasts until Terraform is done with the
+	// token.
+	if count := gitlab.runnerCount(); count != 0 {
+		t.Errorf("expected no runners registered with GitLab after close, got %d", count)
+	}
+}
+
+func TestAccRunnerResourceWriteOnlyToken(t *testing.T) {
+	gitlab := newTestGitLabServer(t)
+
+	// The token is fetched from a runner which is already in runrs.
+	config := func(tokenVersion int) string {
+		return fmt.Sprintf(`
+			resource "%[1]s" "source" {
+			  name         = "source"
+			  url          = "%[4]s"
+			  docker_image = "alpine:latest"
+
+			  registration = {
+			    api_token = "glpat-0123456789abcdef"
+			    group_id  = 7
+			  }
+			}
+
+			ephemeral "peripheral_gitlab_runner_token" "test" {
+			  uuid = %[1]s.source.uuid
+			}
+
+			resource "%[1]s" "%[2]s" {
+			  name         = "%[3]s"
+			  url          = "%[4]s"
+			  docker_image = "alpine:latest"
+
+			  token_wo         = ephemeral.peripheral_gitlab_runner_token.test.token
+			  token_wo_version = %[5]d
+			}`,
+			resourceType,
+			resourceName,
+			initialRunnerName,
+			gitlab.URL,
+			tokenVersion,
+		)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() { testAccPreCheck(t) },
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(version.Must(version.NewVersion("1.11.0"))),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + config(1),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestMatchResourceAttr(
+						resourceCoordinate,
+						"id",
+						regexp.MustCompile(`^[0-9]+$`),
+					),
+					resource.TestCheckNoResourceAttr(
+						resourceCoordinate,
+						"token",
+					),
+					resource.TestCheckNoResourceAttr(
+						resourceCoordinate,
+						"token_wo",
+					),
+				),
+			},
+			{
+				Config: providerConfig + config(2),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						resourceCoordinate,
+						"token_wo_version",
+						"2",
+					),
+					resource.TestCheckNoResourceAttr(
+						resourceCoordinate,
+						"token",
+					),
+				),
+			},
+		},
+	})
+}
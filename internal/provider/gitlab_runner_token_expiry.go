@@ -0,0 +1,95 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// tokenExpiresAt returns when a runner token obtained at tokenObtainedAt
+// expires, or null if tokens don't expire or it is unknown when the token
+// was obtained.
+func (r *GitLabRunnerResource) tokenExpiresAt(tokenObtainedAt types.String) types.String {
+	if r.tokenExpiry == 0 {
+		return types.StringNull()
+	}
+
+	obtainedAt, err := time.Parse(time.RFC3339, tokenObtainedAt.ValueString())
+	if err != nil {
+		return types.StringNull()
+	}
+
+	return types.StringValue(obtainedAt.Add(r.tokenExpiry).UTC().Format(time.RFC3339))
+}
+
+// planTokenExpiry returns the planned value of token_expires_at, and warns
+// about runner tokens which expire soon or errors for expired ones.
+func (r *GitLabRunnerResource) planTokenExpiry(
+	plan *GitLabRunnerResourceModel,
+	state *GitLabRunnerResourceModel,
+	now time.Time,
+) (types.String, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	// Runners are planned before the provider knows token_expiry if it comes
+	// from resources in the same apply.
+	if r.tokenExpiryUnknown {
+		return types.StringUnknown(), diags
+	}
+
+	if r.tokenExpiry == 0 {
+		return types.StringNull(), diags
+	}
+
+	// A new token is obtained by this apply, so the current one doesn't matter.
+	if plan.Token.IsUnknown() || !plan.Token.Equal(state.Token) ||
+		!plan.TokenWoVersion.Equal(state.TokenWoVersion) {
+		return types.StringUnknown(), diags
+	}
+
+	plannedExpiresAt := types.StringUnknown()
+	tokenObtainedAt := state.TokenObtainedAt
+	if !plan.TokenObtainedAt.IsUnknown() {
+		plannedExpiresAt = r.tokenExpiresAt(plan.TokenObtainedAt)
+		tokenObtainedAt = plan.TokenObtainedAt
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, r.tokenExpiresAt(tokenObtainedAt).ValueString())
+	if err != nil {
+		return plannedExpiresAt, diags
+	}
+
+	switch {
+	case !now.Before(expiresAt):
+		diags.AddAttributeError(
+			path.Root("token"),
+			"Runner Token Expired",
+			fmt.Sprintf(
+				"The token of GitLabRunner with ID %d expired at %s. The runner can no longer "+
+					"pick up jobs. Obtain a new token from GitLab, or enable `token_rotation`.",
+				state.Id.ValueInt32(),
+				expiresAt.Format(time.RFC3339),
+			),
+		)
+	case !now.Add(r.tokenExpiryWarning).Before(expiresAt):
+		diags.AddAttributeWarning(
+			path.Root("token"),
+			"Runner Token Expires Soon",
+			fmt.Sprintf(
+				"The token of GitLabRunner with ID %d expires at %s, in %s. Obtain a new token "+
+					"from GitLab, or enable `token_rotation`.",
+				state.Id.ValueInt32(),
+				expiresAt.Format(time.RFC3339),
+				expiresAt.Sub(now).Round(time.Minute),
+			),
+		)
+	}
+
+	return plannedExpiresAt, diags
+}
@@ -0,0 +1,127 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestRunnerResourcePlanTokenExpiry(t *testing.T) {
+	now := time.Date(2024, 8, 23, 23, 23, 23, 0, time.UTC)
+
+	obtainedAt := func(age time.Duration) types.String {
+		return types.StringValue(now.Add(-age).Format(time.RFC3339))
+	}
+
+	testCases := map[string]struct {
+		tokenExpiry        time.Duration
+		tokenExpiryUnknown bool
+		plan               GitLabRunnerResourceModel
+		state              GitLabRunnerResourceModel
+		expectedExpiresAt  types.String
+		expectedSeverity   diag.Severity
+	}{
+		"no token expiry": {
+			plan: GitLabRunnerResourceModel{
+				Token:           types.StringValue("glrt-0123456789_abcdefXYZ"),
+				TokenObtainedAt: obtainedAt(100 * 24 * time.Hour),
+			},
+			state: GitLabRunnerResourceModel{
+				Token:           types.StringValue("glrt-0123456789_abcdefXYZ"),
+				TokenObtainedAt: obtainedAt(100 * 24 * time.Hour),
+			},
+			expectedExpiresAt: types.StringNull(),
+		},
+		"valid token": {
+			tokenExpiry: 90 * 24 * time.Hour,
+			plan: GitLabRunnerResourceModel{
+				Token:           types.StringValue("glrt-0123456789_abcdefXYZ"),
+				TokenObtainedAt: obtainedAt(24 * time.Hour),
+			},
+			state: GitLabRunnerResourceModel{
+				Token:           types.StringValue("glrt-0123456789_abcdefXYZ"),
+				TokenObtainedAt: obtainedAt(24 * time.Hour),
+			},
+			expectedExpiresAt: types.StringValue(now.Add(89 * 24 * time.Hour).Format(time.RFC3339)),
+		},
+		"token expires soon": {
+			tokenExpiry: 90 * 24 * time.Hour,
+			plan: GitLabRunnerResourceModel{
+				Token:           types.StringValue("glrt-0123456789_abcdefXYZ"),
+				TokenObtainedAt: types.StringUnknown(),
+			},
+			state: GitLabRunnerResourceModel{
+				Token:           types.StringValue("glrt-0123456789_abcdefXYZ"),
+				TokenObtainedAt: obtainedAt(88 * 24 * time.Hour),
+			},
+			expectedExpiresAt: types.StringUnknown(),
+			expectedSeverity:  diag.SeverityWarning,
+		},
+		"token expired": {
+			tokenExpiry: 90 * 24 * time.Hour,
+			plan: GitLabRunnerResourceModel{
+				Token:           types.StringValue("glrt-0123456789_abcdefXYZ"),
+				TokenObtainedAt: obtainedAt(90 * 24 * time.Hour),
+			},
+			state: GitLabRunnerResourceModel{
+				Token:           types.StringValue("glrt-0123456789_abcdefXYZ"),
+				TokenObtainedAt: obtainedAt(90 * 24 * time.Hour),
+			},
+			expectedExpiresAt: types.StringValue(now.Format(time.RFC3339)),
+			expectedSeverity:  diag.SeverityError,
+		},
+		"token replaced": {
+			tokenExpiry: 90 * 24 * time.Hour,
+			plan: GitLabRunnerResourceModel{
+				Token:           types.StringUnknown(),
+				TokenObtainedAt: types.StringUnknown(),
+			},
+			state: GitLabRunnerResourceModel{
+				Token:           types.StringValue("glrt-0123456789_abcdefXYZ"),
+				TokenObtainedAt: obtainedAt(100 * 24 * time.Hour),
+			},
+			expectedExpiresAt: types.StringUnknown(),
+		},
+		"unknown token expiry": {
+			tokenExpiryUnknown: true,
+			plan: GitLabRunnerResourceModel{
+				Token:           types.StringValue("glrt-0123456789_abcdefXYZ"),
+				TokenObtainedAt: obtainedAt(24 * time.Hour),
+			},
+			state: GitLabRunnerResourceModel{
+				Token:           types.StringValue("glrt-0123456789_abcdefXYZ"),
+				TokenObtainedAt: obtainedAt(24 * time.Hour),
+			},
+			expectedExpiresAt: types.StringUnknown(),
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			r := &GitLabRunnerResource{
+				tokenExpiry:        testCase.tokenExpiry,
+				tokenExpiryUnknown: testCase.tokenExpiryUnknown,
+				tokenExpiryWarning: defaultTokenExpiryWarning,
+			}
+
+			expiresAt, diags := r.planTokenExpiry(&testCase.plan, &testCase.state, now)
+
+			if !expiresAt.Equal(testCase.expectedExpiresAt) {
+				t.Errorf("expected token_expires_at %s, got %s", testCase.expectedExpiresAt, expiresAt)
+			}
+
+			switch {
+			case testCase.expectedSeverity == diag.SeverityInvalid && len(diags) > 0:
+				t.Errorf("unexpected diagnostics: %v", diags)
+			case testCase.expectedSeverity != diag.SeverityInvalid &&
+				(len(diags) != 1 || diags[0].Severity() != testCase.expectedSeverity):
+				t.Errorf("expected one diagnostic with severity %s, got %v", testCase.expectedSeverity, diags)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/objectvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// pendingTokenKey is the private state key which marks a rotated token that
+// hasn't been pushed to runrs yet.
+const pendingTokenKey = "pending_token"
+
+// privateState is the private state of a resource.
+type privateState interface {
+	GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+}
+
+// tokenPending reports whether the token in state was rotated by an apply
+// which failed to push it to runrs.
+func tokenPending(ctx context.Context, private privateState) (bool, diag.Diagnostics) {
+	value, diags := private.GetKey(ctx, pendingTokenKey)
+	return len(value) > 0, diags
+}
+
+// GitLabRunnerTokenRotationModel describes the token rotation data model.
+type GitLabRunnerTokenRotationModel struct {
+	MaxAge         types.String `tfsdk:"max_age"`
+	RotateTriggers types.Map    `tfsdk:"rotate_triggers"`
+}
+
+// gitLabRunnerTokenRotationAttribute returns the schema of the token
+// rotation attribute.
+func gitLabRunnerTokenRotationAttribute() schema.SingleNestedAttribute {
+	return schema.SingleNestedAttribute{
+		MarkdownDescription: "Rotate the runner token through GitLab when it gets too old, or " +
+			"when any of the triggers change. Requires `registration`.",
+		Optional: true,
+		Attributes: map[string]schema.Attribute{
+			"max_age": schema.StringAttribute{
+				MarkdownDescription: "Maximum age of the runner token, e.g. `2160h` for 90 days; " +
+					"older tokens are rotated by the next apply",
+				Optional: true,
+				Validators: []validator.String{
+					isDuration(),
+				},
+			},
+			"rotate_triggers": schema.MapAttribute{
+				MarkdownDescription: "Arbitrary map of values which, when changed, rotate the runner token",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+		},
+		Validators: []validator.Object{
+			objectvalidator.AlsoRequires(path.MatchRoot("registration")),
+		},
+	}
+}
+
+// rotationDue reports whether the token in state has to be rotated, because
+// it is older than the configured maximum age or a trigger has changed.
+func (m *GitLabRunnerTokenRotationModel) rotationDue(
+	state *GitLabRunnerResourceModel,
+	now time.Time,
+) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	// Enabling rotation for an existing runner doesn't rotate by trigger,
+	// since no trigger has changed yet.
+	if state.TokenRotation != nil {
+		if m.RotateTriggers.IsUnknown() || !m.RotateTriggers.Equal(state.TokenRotation.RotateTriggers) {
+			return true, diags
+		}
+	}
+
+	if m.MaxAge.IsNull() || m.MaxAge.IsUnknown() {
+		return false, diags
+	}
+
+	maxAge, err := time.ParseDuration(m.MaxAge.ValueString())
+	if err != nil {
+		diags.AddAttributeError(
+			path.Root("token_rotation").AtName("max_age"),
+			"Invalid Duration",
+			fmt.Sprintf("Unable to parse max_age: %s", err),
+		)
+		return false, diags
+	}
+
+	obtainedAt, err := time.Parse(time.RFC3339, state.TokenObtainedAt.ValueString())
+	if err != nil {
+		// Without a known token age, leave it to the triggers.
+		return false, diags
+	}
+
+	return now.Sub(obtainedAt) >= maxAge, diags
+}
+
+// rotate resets the runner token in GitLab and stores the new token in data.
+// The current token stops working as soon as this succeeds.
+func (m *GitLabRunnerRegistrationModel) rotate(
+	ctx context.Context,
+	data *GitLabRunnerResourceModel,
+	currentToken types.String,
+) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	client, err := m.client(data.Url)
+	if err != nil {
+		diags.AddError(
+			"GitLab Client Error",
+			fmt.Sprintf("Unable to set up GitLab client: %s", err),
+		)
+		return diags
+	}
+
+	runnerToken, err := client.ResetRunnerToken(ctx, currentToken.ValueString())
+	if err != nil {
+		diags.AddError(
+			"GitLab Client Error",
+			fmt.Sprintf("Unable to rotate token of GitLabRunner with ID %d: %s", data.Id.ValueInt32(), err),
+		)
+		return diags
+	}
+
+	data.Token = types.StringValue(runnerToken.Token)
+	data.TokenObtainedAt = types.StringValue(time.Now().UTC().Format(time.RFC3339))
+
+	tflog.Trace(ctx, fmt.Sprintf("rotated token of GitLabRunner with ID %d", data.Id.ValueInt32()))
+
+	return diags
+}
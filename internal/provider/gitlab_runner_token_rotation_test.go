@@ -0,0 +1,406 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+	"terraform-provider-peripheral/internal/runrstest"
+)
+
+func testRunnerResourceTokenRotationConfig(gitlabURL string, trigger string) string {
+	return fmt.Sprintf(`
+		resource "%s" "%s" {
+		  name         = "%s"
+		  url          = "%s"
+		  docker_image = "alpine:latest"
+
+		  registration = {
+		    api_token = "glpat-0123456789abcdef"
+		    instance  = true
+		  }
+
+		  token_rotation = {
+		    max_age = "2160h"
+		    rotate_triggers = {
+		      trigger = "%s"
+		    }
+		  }
+		}`,
+		resourceType,
+		resourceName,
+		initialRunnerName,
+		gitlabURL,
+		trigger,
+	)
+}
+
+func TestAccRunnerResourceTokenRotation(t *testing.T) {
+	gitlab := newTestGitLabServer(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + testRunnerResourceTokenRotationConfig(gitlab.URL, "initial"),
+				Check: resource.TestCheckResourceAttr(
+					resourceCoordinate,
+					"token",
+					"glrt-test1_abcdefXYZ",
+				),
+			},
+			{
+				Config: providerConfig + testRunnerResourceTokenRotationConfig(gitlab.URL, "rotated"),
+				Check: resource.TestCheckResourceAttr(
+					resourceCoordinate,
+					"token",
+					"glrt-test1-1_abcdefXYZ",
+				),
+			},
+		},
+	})
+}
+
+func TestRunnerResourceTokenRotationDue(t *testing.T) {
+	now := time.Date(2024, 8, 23, 23, 23, 23, 0, time.UTC)
+
+	triggers := func(value string) types.Map {
+		return types.MapValueMust(types.StringType, map[string]attr.Value{
+			"trigger": types.StringValue(value),
+		})
+	}
+
+	testCases := map[string]struct {
+		plan     GitLabRunnerTokenRotationModel
+		state    GitLabRunnerResourceModel
+		expected bool
+	}{
+		"token younger than max age": {
+			plan: GitLabRunnerTokenRotationModel{
+				MaxAge:         types.StringValue("2160h"),
+				RotateTriggers: types.MapNull(types.StringType),
+			},
+			state: GitLabRunnerResourceModel{
+				TokenObtainedAt: types.StringValue(now.Add(-24 * time.Hour).Format(time.RFC3339)),
+			},
+			expected: false,
+		},
+		"token older than max age": {
+			plan: GitLabRunnerTokenRotationModel{
+				MaxAge:         types.StringValue("2160h"),
+				RotateTriggers: types.MapNull(types.StringType),
+			},
+			state: GitLabRunnerResourceModel{
+				TokenObtainedAt: types.StringValue(now.Add(-91 * 24 * time.Hour).Format(time.RFC3339)),
+			},
+			expected: true,
+		},
+		"unknown token age": {
+			plan: GitLabRunnerTokenRotationModel{
+				MaxAge:         types.StringValue("2160h"),
+				RotateTriggers: types.MapNull(types.StringType),
+			},
+			state: GitLabRunnerResourceModel{
+				TokenObtainedAt: types.StringNull(),
+			},
+			expected: false,
+		},
+		"unchanged triggers": {
+			plan: GitLabRunnerTokenRotationModel{
+				MaxAge:         types.StringNull(),
+				RotateTriggers: triggers("initial"),
+			},
+			state: GitLabRunnerResourceModel{
+				TokenRotation: &GitLabRunnerTokenRotationModel{
+					MaxAge:         types.StringNull(),
+					RotateTriggers: triggers("initial"),
+				},
+			},
+			expected: false,
+		},
+		"changed triggers": {
+			plan: GitLabRunnerTokenRotationModel{
+				MaxAge:         types.StringNull(),
+				RotateTriggers: triggers("rotated"),
+			},
+			state: GitLabRunnerResourceModel{
+				TokenRotation: &GitLabRunnerTokenRotationModel{
+					MaxAge:         types.StringNull(),
+					RotateTriggers: triggers("initial"),
+				},
+			},
+			expected: true,
+		},
+		"unknown triggers": {
+			plan: GitLabRunnerTokenRotationModel{
+				MaxAge:         types.StringNull(),
+				RotateTriggers: types.MapUnknown(types.StringType),
+			},
+			state: GitLabRunnerResourceModel{
+				TokenRotation: &GitLabRunnerTokenRotationModel{
+					MaxAge:         types.StringNull(),
+					RotateTriggers: triggers("initial"),
+				},
+			},
+			expected: true,
+		},
+		"rotation newly enabled": {
+			plan: GitLabRunnerTokenRotationModel{
+				MaxAge:         types.StringNull(),
+				RotateTriggers: triggers("initial"),
+			},
+			state:    GitLabRunnerResourceModel{},
+			expected: false,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			actual, diags := testCase.plan.rotationDue(&testCase.state, now)
+			if diags.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+
+			if actual != testCase.expected {
+				t.Errorf("expected %t, got %t", testCase.expected, actual)
+			}
+		})
+	}
+}
+
+// testRunnerResourceValue returns the model as a value of the resource.
+func testRunnerResourceValue(t *testing.T, model GitLabRunnerResourceModel) *tfprotov6.DynamicValue {
+	t.Helper()
+
+	state := testRunnerResourceSchemaState(t)
+	if diags := state.Set(context.Background(), &model); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	value, err := tfprotov6.NewDynamicValue(state.Raw.Type(), state.Raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &value
+}
+
+// testRunnerResourceModel returns the value of the resource as a model.
+func testRunnerResourceModel(t *testing.T, value *tfprotov6.DynamicValue) GitLabRunnerResourceModel {
+	t.Helper()
+
+	state := testRunnerResourceSchemaState(t)
+
+	raw, err := value.Unmarshal(state.Raw.Type())
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Raw = raw
+
+	var model GitLabRunnerResourceModel
+	if diags := state.Get(context.Background(), &model); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	return model
+}
+
+func TestRunnerResourceTokenRotationPushFailed(t *testing.T) {
+	ctx := context.Background()
+
+	gitlab := newTestGitLabServer(t)
+	gitlab.runners[1] = "glrt-test1_abcdefXYZ"
+	gitlab.nextId = 2
+
+	runrsServer := runrstest.NewServer(testRunrsSecret)
+	t.Cleanup(runrsServer.Close)
+
+	auth := newRunrsAuth(staticCredential(testRunrsSecret), jwt.MapClaims{})
+	client, err := runrs.NewClientWithResponses(runrsServer.URL, runrs.WithRequestEditorFn(auth.Intercept))
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := client.CreateWithResponse(ctx, runrs.GitLabRunner{
+		Id:          1,
+		Url:         gitlab.URL,
+		Token:       "glrt-test1_abcdefXYZ",
+		DockerImage: "alpine:latest",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.JSON201 == nil {
+		t.Fatalf("unexpected create response %d: %s", created.StatusCode(), created.Body)
+	}
+
+	server, diags := testConfigureProvider(t, map[string]tftypes.Value{
+		"endpoint": tftypes.NewValue(tftypes.String, runrsServer.URL),
+	})
+	for _, d := range diags {
+		if d.Severity == tfprotov6.DiagnosticSeverityError {
+			t.Fatalf("unable to configure provider: %s: %s", d.Summary, d.Detail)
+		}
+	}
+
+	triggers := func(value string) types.Map {
+		return types.MapValueMust(types.StringType, map[string]attr.Value{
+			"trigger": types.StringValue(value),
+		})
+	}
+
+	nullState := testRunnerResourceSchemaState(t)
+	stateType := nullState.Raw.Type().(tftypes.Object)
+	values := map[string]tftypes.Value{}
+	for name, typ := range stateType.AttributeTypes {
+		values[name] = tftypes.NewValue(typ, nil)
+	}
+	nullState.Raw = tftypes.NewValue(stateType, values)
+
+	var state GitLabRunnerResourceModel
+	if diags := nullState.Get(ctx, &state); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	state.Url = types.StringValue(gitlab.URL)
+	state.DockerImage = types.StringValue("alpine:latest")
+	state.Registration = &GitLabRunnerRegistrationModel{
+		ApiToken:  types.StringValue("glpat-0123456789abcdef"),
+		Instance:  types.BoolValue(true),
+		GroupId:   types.Int64Null(),
+		ProjectId: types.Int64Null(),
+	}
+	state.TokenRotation = &GitLabRunnerTokenRotationModel{
+		MaxAge:         types.StringNull(),
+		RotateTriggers: triggers("rotated"),
+	}
+	config := testRunnerResourceValue(t, state)
+
+	state.FromGitLabRunner(created.JSON201)
+	state.TokenRotation = &GitLabRunnerTokenRotationModel{
+		MaxAge:         types.StringNull(),
+		RotateTriggers: triggers("initial"),
+	}
+	priorState := testRunnerResourceValue(t, state)
+
+	state.TokenRotation.RotateTriggers = triggers("rotated")
+	proposedNewState := testRunnerResourceValue(t, state)
+
+	// The token is rotated, but pushing it to runrs fails.
+	planResp, err := server.PlanResourceChange(ctx, &tfprotov6.PlanResourceChangeRequest{
+		TypeName:         "peripheral_gitlab_runner",
+		PriorState:       priorState,
+		ProposedNewState: proposedNewState,
+		Config:           config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(planResp.Diagnostics) > 0 {
+		t.Fatalf("unexpected diagnostics: %v", planResp.Diagnostics)
+	}
+
+	runrsServer.InjectError(runrstest.OperationUpdate, runrs.InternalError)
+
+	applyResp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:       "peripheral_gitlab_runner",
+		PriorState:     priorState,
+		PlannedState:   planResp.PlannedState,
+		Config:         config,
+		PlannedPrivate: planResp.PlannedPrivate,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applyResp.Diagnostics) == 0 {
+		t.Fatal("expected update to fail")
+	}
+
+	applied := testRunnerResourceModel(t, applyResp.NewState)
+	if actual := applied.Token.ValueString(); actual != "glrt-test1-1_abcdefXYZ" {
+		t.Errorf("expected rotated token in state, got %s", actual)
+	}
+	if !applied.TokenRotation.RotateTriggers.Equal(triggers("rotated")) {
+		t.Errorf("expected rotated triggers in state, got %s", applied.TokenRotation.RotateTriggers)
+	}
+
+	runrsServer.ClearErrors()
+
+	// Refreshing keeps the rotated token, although runrs still has the old
+	// one.
+	readResp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+		TypeName:     "peripheral_gitlab_runner",
+		CurrentState: applyResp.NewState,
+		Private:      applyResp.Private,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(readResp.Diagnostics) > 0 {
+		t.Fatalf("unexpected diagnostics: %v", readResp.Diagnostics)
+	}
+
+	read := testRunnerResourceModel(t, readResp.NewState)
+	if !read.Token.Equal(applied.Token) || !read.TokenObtainedAt.Equal(applied.TokenObtainedAt) {
+		t.Errorf("expected rotated token to be kept, got %s obtained at %s", read.Token, read.TokenObtainedAt)
+	}
+
+	// The next plan pushes the rotated token instead of rotating again.
+	planResp, err = server.PlanResourceChange(ctx, &tfprotov6.PlanResourceChangeRequest{
+		TypeName:         "peripheral_gitlab_runner",
+		PriorState:       readResp.NewState,
+		ProposedNewState: readResp.NewState,
+		Config:           config,
+		PriorPrivate:     readResp.Private,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(planResp.Diagnostics) > 0 {
+		t.Fatalf("unexpected diagnostics: %v", planResp.Diagnostics)
+	}
+
+	planned := testRunnerResourceModel(t, planResp.PlannedState)
+	if !planned.Token.Equal(applied.Token) {
+		t.Errorf("expected rotated token to be planned, got %s", planned.Token)
+	}
+	if !planned.TokenObtainedAt.IsUnknown() {
+		t.Errorf("expected token push to be planned, got %s", planned.TokenObtainedAt)
+	}
+
+	applyResp, err = server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:       "peripheral_gitlab_runner",
+		PriorState:     readResp.NewState,
+		PlannedState:   planResp.PlannedState,
+		Config:         config,
+		PlannedPrivate: planResp.PlannedPrivate,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applyResp.Diagnostics) > 0 {
+		t.Fatalf("unexpected diagnostics: %v", applyResp.Diagnostics)
+	}
+
+	runner, ok := runrsServer.Runner(applied.Uuid.ValueString())
+	if !ok || runner.Token != "glrt-test1-1_abcdefXYZ" {
+		t.Errorf("expected rotated token in runrs, got %v", runner)
+	}
+	if gitlab.rotations != 1 {
+		t.Errorf("expected 1 rotation, got %d", gitlab.rotations)
+	}
+	if len(applyResp.Private) > 0 {
+		t.Errorf("expected no pending token, got private state %s", applyResp.Private)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"terraform-provider-peripheral/internal/clients/gitlab"
+)
+
+// gitLabRunnerTokenWriteOnlyAttribute returns the schema of the write-only
+// token attribute.
+func gitLabRunnerTokenWriteOnlyAttribute() schema.StringAttribute {
+	return schema.StringAttribute{
+		MarkdownDescription: "Token for GitLabRunner registration which is sent to runrs, but never " +
+			"stored in the plan or state, e.g. from the `peripheral_gitlab_runner_token` ephemeral " +
+			"resource. Unless `id` is set, it is looked up from GitLab by the token. Changes are only " +
+			"sent when `token_wo_version` changes. Requires Terraform 1.11 or later.",
+		Optional:  true,
+		Sensitive: true,
+		WriteOnly: true,
+	}
+}
+
+// gitLabRunnerTokenWriteOnlyVersionAttribute returns the schema of the
+// attribute which triggers sending a changed write-only token.
+func gitLabRunnerTokenWriteOnlyVersionAttribute() schema.Int64Attribute {
+	return schema.Int64Attribute{
+		MarkdownDescription: "Version of `token_wo`; change it to send a new token to runrs",
+		Optional:            true,
+		Validators: []validator.Int64{
+			int64validator.AlsoRequires(path.MatchRoot("token_wo")),
+		},
+	}
+}
+
+// writeOnlyToken returns the configured token_wo, which is null if the token
+// is not write-only.
+func writeOnlyToken(ctx context.Context, config tfsdk.Config) (types.String, diag.Diagnostics) {
+	var token types.String
+	diags := config.GetAttribute(ctx, path.Root("token_wo"), &token)
+	return token, diags
+}
+
+// runnerIdForToken looks up the ID of the runner which token belongs to in
+// the GitLab instance at url, since ephemeral values such as the ID from the
+// `peripheral_gitlab_runner_token` ephemeral resource can't be configured.
+func runnerIdForToken(ctx context.Context, url types.String, token types.String) (types.Int32, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	client, err := gitlab.NewClient(url.ValueString(), "")
+	if err != nil {
+		diags.AddError(
+			"GitLab Client Error",
+			fmt.Sprintf("Unable to set up GitLab client: %s", err),
+		)
+		return types.Int32Unknown(), diags
+	}
+
+	runner, err := client.VerifyRunner(ctx, token.ValueString())
+	if err != nil {
+		diags.AddAttributeError(
+			path.Root("token_wo"),
+			"GitLab Client Error",
+			fmt.Sprintf("Unable to look up the GitLabRunner of token_wo in GitLab: %s", err),
+		)
+		return types.Int32Unknown(), diags
+	}
+
+	if runner.Id < 0 || runner.Id > math.MaxInt32 {
+		diags.AddError(
+			"GitLab Client Error",
+			fmt.Sprintf("GitLab returned runner ID %d, which is not a valid 32-bit integer", runner.Id),
+		)
+		return types.Int32Unknown(), diags
+	}
+
+	return types.Int32Value(int32(runner.Id)), diags
+}
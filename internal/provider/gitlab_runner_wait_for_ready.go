@@ -0,0 +1,213 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"terraform-provider-peripheral/internal/clients/gitlab"
+)
+
+// defaultWaitForReadyTimeout is used when wait_for_ready.timeout is not set.
+const defaultWaitForReadyTimeout = 5 * time.Minute
+
+// waitForReadyPollInterval is how often GitLab is asked for the runner
+// status while waiting for it to be ready.
+var waitForReadyPollInterval = 5 * time.Second
+
+// GitLabRunnerWaitForReadyModel describes the wait for ready data model.
+type GitLabRunnerWaitForReadyModel struct {
+	Timeout  types.String `tfsdk:"timeout"`
+	ApiToken types.String `tfsdk:"api_token"`
+}
+
+// gitLabRunnerWaitForReadyAttribute returns the schema of the wait for ready
+// attribute.
+func gitLabRunnerWaitForReadyAttribute() schema.SingleNestedAttribute {
+	return schema.SingleNestedAttribute{
+		MarkdownDescription: "Wait after create and update until the runner has contacted GitLab. " +
+			"If it doesn't in time, the apply fails with the runner's last status, and the next apply " +
+			"retries. runrs doesn't report whether runners have contacted GitLab, so the status is " +
+			"read from GitLab, which needs a GitLab access token.",
+		Optional: true,
+		Attributes: map[string]schema.Attribute{
+			"timeout": schema.StringAttribute{
+				MarkdownDescription: "How long to wait for the runner, e.g. `10m`; defaults to `5m`",
+				Optional:            true,
+				Validators: []validator.String{
+					isDuration(),
+				},
+			},
+			"api_token": schema.StringAttribute{
+				MarkdownDescription: "GitLab access token which can read the runner; defaults to " +
+					"`registration.api_token`",
+				Optional:  true,
+				Sensitive: true,
+			},
+		},
+	}
+}
+
+// validateWaitForReadyApiToken ensures that there is a GitLab access token
+// to wait for the runner with.
+func validateWaitForReadyApiToken(ctx context.Context, config tfsdk.Config) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	var waitForReady, registration types.Object
+	diags.Append(config.GetAttribute(ctx, path.Root("wait_for_ready"), &waitForReady)...)
+	diags.Append(config.GetAttribute(ctx, path.Root("registration"), &registration)...)
+	if diags.HasError() || waitForReady.IsNull() || waitForReady.IsUnknown() || !registration.IsNull() {
+		return diags
+	}
+
+	apiTokenPath := path.Root("wait_for_ready").AtName("api_token")
+
+	var apiToken types.String
+	diags.Append(config.GetAttribute(ctx, apiTokenPath, &apiToken)...)
+	if diags.HasError() {
+		return diags
+	}
+
+	if apiToken.IsNull() {
+		diags.AddAttributeError(
+			apiTokenPath,
+			"Missing Attribute Configuration",
+			"`api_token` must be set to wait for runners which are not registered through `registration`.",
+		)
+	}
+
+	return diags
+}
+
+// wait polls GitLab until the runner is online, or the timeout expires. The
+// status of runners is only known to GitLab, not to runrs, so this needs a
+// GitLab access token.
+func (m *GitLabRunnerWaitForReadyModel) wait(
+	ctx context.Context,
+	data *GitLabRunnerResourceModel,
+) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	timeout := defaultWaitForReadyTimeout
+	if !m.Timeout.IsNull() {
+		var err error
+		timeout, err = time.ParseDuration(m.Timeout.ValueString())
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root("wait_for_ready").AtName("timeout"),
+				"Invalid Duration",
+				fmt.Sprintf("Unable to parse timeout: %s", err),
+			)
+			return diags
+		}
+	}
+
+	apiToken := m.ApiToken
+	if apiToken.IsNull() && data.Registration != nil {
+		apiToken = data.Registration.ApiToken
+	}
+	if apiToken.ValueString() == "" {
+		diags.AddAttributeError(
+			path.Root("wait_for_ready").AtName("api_token"),
+			"Missing GitLab Access Token",
+			"Waiting for the runner reads its status from GitLab, since runrs doesn't report it. "+
+				"Set `api_token`, or register the runner through `registration`.",
+		)
+		return diags
+	}
+
+	client, err := gitlab.NewClient(data.Url.ValueString(), apiToken.ValueString())
+	if err != nil {
+		diags.AddError(
+			"GitLab Client Error",
+			fmt.Sprintf("Unable to set up GitLab client: %s", err),
+		)
+		return diags
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(waitForReadyPollInterval)
+	defer ticker.Stop()
+
+	id := int64(data.Id.ValueInt32())
+	lastStatus := "unknown"
+
+	for {
+		runner, err := client.GetRunner(ctx, id)
+
+		// Waiting doesn't help if GitLab doesn't accept the token.
+		var apiErr *gitlab.Error
+		if errors.As(err, &apiErr) &&
+			(apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden) {
+			diags.AddAttributeError(
+				path.Root("wait_for_ready").AtName("api_token"),
+				"GitLab Authentication Failed",
+				fmt.Sprintf(
+					"Unable to read the status of GitLabRunner with ID %d from GitLab: %s. The GitLab "+
+						"access token must be able to read the runner.",
+					id,
+					err,
+				),
+			)
+			return diags
+		}
+
+		switch {
+		case err == nil && runner.Status == gitlab.StatusOnline:
+			tflog.Trace(ctx, fmt.Sprintf("GitLabRunner with ID %d is ready", id))
+			return diags
+		case err == nil:
+			lastStatus = string(runner.Status)
+		case ctx.Err() == nil:
+			lastStatus = err.Error()
+		}
+
+		tflog.Debug(ctx, fmt.Sprintf("waiting for GitLabRunner with ID %d, status: %s", id, lastStatus))
+
+		select {
+		case <-ctx.Done():
+			diags.AddError(
+				"Runner Not Ready",
+				fmt.Sprintf(
+					"GitLabRunner with ID %d didn't contact GitLab within %s, last status: %s. Check "+
+						"that runrs can pull `docker_image` and start the runner. The next apply "+
+						"retries.",
+					id,
+					timeout,
+					lastStatus,
+				),
+			)
+			return diags
+		case <-ticker.C:
+		}
+	}
+}
+
+// waitForReady waits for the runner if wait_for_ready is set, and records
+// whether it is ready in the model.
+func (m *GitLabRunnerResourceModel) waitForReady(ctx context.Context) diag.Diagnostics {
+	if m.WaitForReady == nil {
+		m.Ready = types.BoolNull()
+		return nil
+	}
+
+	diags := m.WaitForReady.wait(ctx, m)
+	m.Ready = types.BoolValue(!diags.HasError())
+
+	return diags
+}
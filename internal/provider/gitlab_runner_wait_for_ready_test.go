@@ -0,0 +1,134 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestRunnerResourceWaitForReady(t *testing.T) {
+	pollInterval := waitForReadyPollInterval
+	waitForReadyPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { waitForReadyPollInterval = pollInterval })
+
+	testCases := map[string]struct {
+		status        string
+		id            int32
+		apiToken      string
+		expectedReady bool
+		expectedError string
+	}{
+		"online": {
+			status:        "online",
+			id:            1,
+			apiToken:      "glpat-0123456789abcdef",
+			expectedReady: true,
+		},
+		"never contacted": {
+			status:        "never_contacted",
+			id:            1,
+			apiToken:      "glpat-0123456789abcdef",
+			expectedError: "last status: never_contacted",
+		},
+		"not found": {
+			status:        "online",
+			id:            2,
+			apiToken:      "glpat-0123456789abcdef",
+			expectedError: "404 Not Found",
+		},
+		"without api_token": {
+			status:        "online",
+			id:            1,
+			expectedError: "Set `api_token`",
+		},
+		"unauthorized": {
+			status:        "online",
+			id:            1,
+			apiToken:      "revoked",
+			expectedError: "401 Unauthorized",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			gitlab := newTestGitLabServer(t)
+			gitlab.status = testCase.status
+			gitlab.runners[1] = "glrt-test1_abcdefXYZ"
+
+			apiToken := types.StringNull()
+			if testCase.apiToken != "" {
+				apiToken = types.StringValue(testCase.apiToken)
+			}
+
+			data := GitLabRunnerResourceModel{
+				Id:  types.Int32Value(testCase.id),
+				Url: types.StringValue(gitlab.URL),
+				WaitForReady: &GitLabRunnerWaitForReadyModel{
+					Timeout:  types.StringValue("50ms"),
+					ApiToken: apiToken,
+				},
+			}
+
+			diags := data.waitForReady(context.Background())
+
+			if data.Ready.ValueBool() != testCase.expectedReady {
+				t.Errorf("expected ready to be %t, got %s", testCase.expectedReady, data.Ready)
+			}
+
+			switch {
+			case testCase.expectedError == "" && diags.HasError():
+				t.Errorf("unexpected errors: %v", diags)
+			case testCase.expectedError != "" && !diags.HasError():
+				t.Errorf("expected error %q", testCase.expectedError)
+			case testCase.expectedError != "" && !strings.Contains(diags[0].Detail(), testCase.expectedError):
+				t.Errorf("expected error %q, got %q", testCase.expectedError, diags[0].Detail())
+			}
+		})
+	}
+}
+
+func TestAccRunnerResourceWaitForReady(t *testing.T) {
+	gitlab := newTestGitLabServer(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+					resource "%s" "%s" {
+					  name         = "%s"
+					  url          = "%s"
+					  docker_image = "alpine:latest"
+
+					  registration = {
+					    api_token = "glpat-0123456789abcdef"
+					    instance  = true
+					  }
+
+					  wait_for_ready = {
+					    timeout = "1m"
+					  }
+					}`,
+					resourceType,
+					resourceName,
+					initialRunnerName,
+					gitlab.URL,
+				),
+				Check: resource.TestCheckResourceAttr(
+					resourceCoordinate,
+					"ready",
+					"true",
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,230 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+// peripheralProviderOIDCModel describes the oidc block of the provider.
+type peripheralProviderOIDCModel struct {
+	TokenFile    types.String `tfsdk:"token_file"`
+	TokenEnv     types.String `tfsdk:"token_env"`
+	Audience     types.String `tfsdk:"audience"`
+	ExchangePath types.String `tfsdk:"exchange_path"`
+}
+
+// oidcIdentity obtains the credential for runrs from an OIDC ID token, such as
+// the id_tokens of GitLab CI jobs. The ID token is either sent to runrs as the
+// bearer token, or exchanged for an access token of runrs as in RFC 8693.
+//
+// The ID token is read again from its file or environment variable when it,
+// or the access token it was exchanged for, expires, so that ID tokens
+// refreshed by the CI system are picked up.
+type oidcIdentity struct {
+	tokenFile string
+	tokenEnv  string
+	audience  string
+
+	// exchangeURL is where the ID token is exchanged, or empty if it is sent
+	// as the bearer token.
+	exchangeURL string
+	doer        runrs.HttpRequestDoer
+
+	mu     sync.Mutex
+	cached runrsCredential
+}
+
+// newOIDCIdentity returns the oidcIdentity of the oidc block, which exchanges
+// ID tokens at runrs at the first of the endpoints which is reachable, through
+// doer. doer shouldn't log requests, as they carry the ID token in their body.
+func newOIDCIdentity(
+	model peripheralProviderOIDCModel,
+	doer runrs.HttpRequestDoer,
+	endpoints []string,
+) (*oidcIdentity, error) {
+	tokenFile, err := expandHome(model.TokenFile.ValueString())
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &oidcIdentity{
+		tokenFile: tokenFile,
+		tokenEnv:  model.TokenEnv.ValueString(),
+		audience:  model.Audience.ValueString(),
+	}
+
+	if !model.ExchangePath.IsNull() {
+		failover, err := newEndpointFailover(doer, endpoints)
+		if err != nil {
+			return nil, err
+		}
+
+		identity.doer = failover
+		identity.exchangeURL = strings.TrimSuffix(endpoints[0], "/") + "/" +
+			strings.TrimPrefix(model.ExchangePath.ValueString(), "/")
+	}
+
+	return identity, nil
+}
+
+// credential returns the cached credential, or obtains it again if there is
+// none or it expires soon.
+func (o *oidcIdentity) credential(ctx context.Context) (runrsCredential, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.cached.bearerToken != "" &&
+		(o.cached.expiresAt.IsZero() || time.Until(o.cached.expiresAt) > credentialExpiryMargin) {
+		return o.cached, nil
+	}
+
+	idToken, expiresAt, err := o.idToken()
+	if err != nil {
+		return runrsCredential{}, err
+	}
+
+	credential := runrsCredential{bearerToken: idToken, expiresAt: expiresAt}
+	if o.exchangeURL != "" {
+		credential, err = o.exchange(ctx, idToken, expiresAt)
+		if err != nil {
+			return runrsCredential{}, err
+		}
+	}
+
+	o.cached = credential
+
+	return credential, nil
+}
+
+// idToken reads the ID token, and returns when it expires. Its signature is
+// left to runrs, but an expired ID token or one for another audience fails
+// here rather than with a 401 of runrs.
+func (o *oidcIdentity) idToken() (string, time.Time, error) {
+	var idToken string
+	if o.tokenFile != "" {
+		content, err := os.ReadFile(o.tokenFile)
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("unable to read ID token: %w", err)
+		}
+		idToken = strings.TrimSpace(string(content))
+	} else {
+		idToken = strings.TrimSpace(os.Getenv(o.tokenEnv))
+		if idToken == "" {
+			return "", time.Time{}, fmt.Errorf("no ID token in %s", o.tokenEnv)
+		}
+	}
+
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(idToken, claims); err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	var expiresAt time.Time
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		expiresAt = exp.Time
+		if time.Now().After(expiresAt) {
+			return "", time.Time{}, fmt.Errorf("ID token expired at %s", expiresAt.UTC().Format(time.RFC3339))
+		}
+	}
+
+	if o.audience != "" {
+		audience, err := claims.GetAudience()
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("invalid ID token: %w", err)
+		}
+		if !slices.Contains(audience, o.audience) {
+			return "", time.Time{}, fmt.Errorf("ID token is for audience %v, not %q", []string(audience), o.audience)
+		}
+	}
+
+	return idToken, expiresAt, nil
+}
+
+// oidcTokenExchangeResponse is the response of a token exchange of RFC 8693,
+// or an error of RFC 6749.
+type oidcTokenExchangeResponse struct {
+	AccessToken      string `json:"access_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchange exchanges the ID token, which expires at idTokenExpiresAt, for an
+// access token of runrs.
+func (o *oidcIdentity) exchange(
+	ctx context.Context,
+	idToken string,
+	idTokenExpiresAt time.Time,
+) (runrsCredential, error) {
+	form := url.Values{
+		"grant_type":         {"urn:ietf:params:oauth:grant-type:token-exchange"},
+		"subject_token":      {idToken},
+		"subject_token_type": {"urn:ietf:params:oauth:token-type:id_token"},
+	}
+	if o.audience != "" {
+		form.Set("audience", o.audience)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.exchangeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return runrsCredential{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	tflog.Debug(ctx, "exchanging ID token", map[string]interface{}{
+		"url": o.exchangeURL,
+	})
+
+	resp, err := o.doer.Do(req)
+	if err != nil {
+		return runrsCredential{}, fmt.Errorf("token exchange failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body oidcTokenExchangeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return runrsCredential{}, fmt.Errorf("invalid response of token exchange: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if body.Error != "" {
+			return runrsCredential{}, fmt.Errorf(
+				"token exchange failed with %s: %s: %s",
+				resp.Status,
+				body.Error,
+				body.ErrorDescription,
+			)
+		}
+		return runrsCredential{}, fmt.Errorf("token exchange failed with %s", resp.Status)
+	}
+	if body.AccessToken == "" {
+		return runrsCredential{}, errors.New("no access_token in response of token exchange")
+	}
+
+	// Access tokens without expires_in are assumed to expire with the ID
+	// token.
+	expiresAt := idTokenExpiresAt
+	if body.ExpiresIn > 0 {
+		expiresAt = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+
+	return runrsCredential{bearerToken: body.AccessToken, expiresAt: expiresAt}, nil
+}
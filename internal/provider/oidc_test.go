@@ -0,0 +1,307 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+
+	"terraform-provider-peripheral/internal/runrstest"
+)
+
+const testOIDCAudience = "runrs"
+
+const testOIDCTokenEnv = "PERIPHERAL_TEST_ID_TOKEN"
+
+func TestOIDCIdentity(t *testing.T) {
+	server := runrstest.NewServer(testRunrsSecret)
+	t.Cleanup(server.Close)
+
+	issuer := runrstest.NewIssuer()
+	server.TrustIssuer(issuer, testOIDCAudience)
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	testCases := map[string]struct {
+		idTokenAudience  string
+		idTokenExpiresIn time.Duration
+		idToken          string
+		audience         string
+		exchangePath     string
+		endpoints        []string
+		expectedError    string
+	}{
+		"forward": {
+			audience: testOIDCAudience,
+		},
+		"forward without audience": {},
+		"exchange": {
+			audience:     testOIDCAudience,
+			exchangePath: runrstest.TokenExchangePath,
+		},
+		"exchange at second endpoint": {
+			audience:     testOIDCAudience,
+			exchangePath: runrstest.TokenExchangePath,
+			endpoints:    []string{unreachable.URL, server.URL},
+		},
+		"wrong audience": {
+			idTokenAudience: "vault",
+			audience:        testOIDCAudience,
+			expectedError:   `not "runrs"`,
+		},
+		"expired": {
+			idTokenExpiresIn: -time.Minute,
+			expectedError:    "ID token expired",
+		},
+		"invalid": {
+			idToken:       "not a JWT",
+			expectedError: "invalid ID token",
+		},
+		"missing": {
+			idToken:       " ",
+			expectedError: "no ID token in " + testOIDCTokenEnv,
+		},
+		"exchange rejected": {
+			idTokenAudience: "vault",
+			exchangePath:    runrstest.TokenExchangePath,
+			expectedError:   "invalid_grant",
+		},
+		"exchange missing": {
+			exchangePath:  "oauth/missing",
+			expectedError: "404 Not Found",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			idToken := testCase.idToken
+			if idToken == "" {
+				audience := testOIDCAudience
+				if testCase.idTokenAudience != "" {
+					audience = testCase.idTokenAudience
+				}
+				expiresIn := time.Hour
+				if testCase.idTokenExpiresIn != 0 {
+					expiresIn = testCase.idTokenExpiresIn
+				}
+
+				var err error
+				idToken, err = issuer.IDToken(audience, time.Now().Add(expiresIn))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			t.Setenv(testOIDCTokenEnv, idToken)
+
+			model := peripheralProviderOIDCModel{
+				TokenEnv:     types.StringValue(testOIDCTokenEnv),
+				Audience:     types.StringNull(),
+				ExchangePath: types.StringNull(),
+			}
+			if testCase.audience != "" {
+				model.Audience = types.StringValue(testCase.audience)
+			}
+			if testCase.exchangePath != "" {
+				model.ExchangePath = types.StringValue(testCase.exchangePath)
+			}
+
+			endpoints := []string{server.URL}
+			if testCase.endpoints != nil {
+				endpoints = testCase.endpoints
+			}
+
+			identity, err := newOIDCIdentity(model, http.DefaultClient, endpoints)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			credential, err := identity.credential(context.Background())
+
+			if testCase.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+					t.Errorf("expected error containing %q, got %v", testCase.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if testCase.exchangePath == "" && credential.bearerToken != idToken {
+				t.Errorf("expected the ID token to be forwarded, got %q", credential.bearerToken)
+			}
+			if testCase.exchangePath != "" && (credential.bearerToken == "" || credential.bearerToken == idToken) {
+				t.Errorf("expected the ID token to be exchanged, got %q", credential.bearerToken)
+			}
+			if credential.secret != "" || credential.expiresAt.IsZero() {
+				t.Errorf("expected an expiring bearer token, got %+v", credential)
+			}
+		})
+	}
+}
+
+func TestOIDCIdentityRefresh(t *testing.T) {
+	server := runrstest.NewServer(testRunrsSecret)
+	t.Cleanup(server.Close)
+
+	issuer := runrstest.NewIssuer()
+	server.TrustIssuer(issuer, testOIDCAudience)
+
+	testCases := map[string]struct {
+		expiresIn         time.Duration
+		exchangePath      string
+		expectRefresh     bool
+		expectedExchanges int
+	}{
+		"valid": {
+			expiresIn: time.Hour,
+		},
+		"expiring": {
+			expiresIn:     credentialExpiryMargin / 2,
+			expectRefresh: true,
+		},
+		"exchanged": {
+			expiresIn:         time.Hour,
+			exchangePath:      runrstest.TokenExchangePath,
+			expectedExchanges: 1,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tokenFile := filepath.Join(t.TempDir(), "id_token")
+			writeIDToken := func() string {
+				t.Helper()
+
+				idToken, err := issuer.IDToken(testOIDCAudience, time.Now().Add(testCase.expiresIn))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(tokenFile, []byte(idToken+"\n"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+
+				return idToken
+			}
+
+			model := peripheralProviderOIDCModel{
+				TokenFile:    types.StringValue(tokenFile),
+				Audience:     types.StringValue(testOIDCAudience),
+				ExchangePath: types.StringNull(),
+			}
+			if testCase.exchangePath != "" {
+				model.ExchangePath = types.StringValue(testCase.exchangePath)
+			}
+
+			identity, err := newOIDCIdentity(model, http.DefaultClient, []string{server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			exchangesBefore := server.TokenExchanges()
+
+			writeIDToken()
+			first, err := identity.credential(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The CI system refreshes the ID token. Tokens are signed with
+			// second precision, so the new one has to be issued a second later
+			// to differ.
+			time.Sleep(time.Second)
+			refreshed := writeIDToken()
+
+			second, err := identity.credential(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if testCase.expectRefresh && second.bearerToken != refreshed {
+				t.Error("expected the refreshed ID token to be read")
+			}
+			if !testCase.expectRefresh && second.bearerToken != first.bearerToken {
+				t.Error("expected the credential to be cached while it is valid")
+			}
+			if exchanges := server.TokenExchanges() - exchangesBefore; exchanges != testCase.expectedExchanges {
+				t.Errorf("expected %d token exchanges, got %d", testCase.expectedExchanges, exchanges)
+			}
+		})
+	}
+}
+
+func TestProviderConfigureOIDC(t *testing.T) {
+	server := runrstest.NewServer(testRunrsSecret)
+	t.Cleanup(server.Close)
+
+	issuer := runrstest.NewIssuer()
+	server.TrustIssuer(issuer, testOIDCAudience)
+
+	idToken, err := issuer.IDToken(testOIDCAudience, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(testOIDCTokenEnv, idToken)
+
+	oidcType := tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+		"token_file":    tftypes.String,
+		"token_env":     tftypes.String,
+		"audience":      tftypes.String,
+		"exchange_path": tftypes.String,
+	}}
+
+	testCases := map[string]struct {
+		audience        string
+		exchangePath    interface{}
+		expectedSummary string
+	}{
+		"forward": {
+			audience: testOIDCAudience,
+		},
+		"exchange": {
+			audience:     testOIDCAudience,
+			exchangePath: "oauth/token",
+		},
+		"wrong audience": {
+			audience:        "vault",
+			expectedSummary: "OIDC Authentication Failed",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, diags := testConfigureProvider(t, map[string]tftypes.Value{
+				"endpoint": tftypes.NewValue(tftypes.String, server.URL),
+				"token":    tftypes.NewValue(tftypes.String, nil),
+				"oidc": tftypes.NewValue(oidcType, map[string]tftypes.Value{
+					"token_file":    tftypes.NewValue(tftypes.String, nil),
+					"token_env":     tftypes.NewValue(tftypes.String, testOIDCTokenEnv),
+					"audience":      tftypes.NewValue(tftypes.String, testCase.audience),
+					"exchange_path": tftypes.NewValue(tftypes.String, testCase.exchangePath),
+				}),
+			})
+
+			if testCase.expectedSummary == "" {
+				if len(diags) > 0 {
+					t.Errorf("unexpected diagnostics: %v", diags)
+				}
+				return
+			}
+
+			if len(diags) != 1 || diags[0].Summary != testCase.expectedSummary {
+				t.Errorf("expected %q, got %v", testCase.expectedSummary, diags)
+			}
+		})
+	}
+}
@@ -6,22 +6,38 @@ package provider
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
 	runrs "terraform-provider-peripheral/internal/clients"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/providervalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/oapi-codegen/oapi-codegen/v2/pkg/securityprovider"
 )
 
 // Ensure peripheralProvider satisfies various provider interfaces.
 var _ provider.Provider = &peripheralProvider{}
 var _ provider.ProviderWithFunctions = &peripheralProvider{}
+var _ provider.ProviderWithEphemeralResources = &peripheralProvider{}
+var _ provider.ProviderWithConfigValidators = &peripheralProvider{}
 
 // peripheralProvider defines the provider implementation.
 type peripheralProvider struct {
@@ -33,8 +49,125 @@ type peripheralProvider struct {
 
 // peripheralProviderModel describes the provider data model.
 type peripheralProviderModel struct {
-	Endpoint types.String `tfsdk:"endpoint"`
-	Token    types.String `tfsdk:"token"`
+	Endpoint                  types.String `tfsdk:"endpoint"`
+	Endpoints                 types.List   `tfsdk:"endpoints"`
+	Token                     types.String `tfsdk:"token"`
+	TokenCommand              types.List   `tfsdk:"token_command"`
+	OIDC                      types.Object `tfsdk:"oidc"`
+	AuthMode                  types.String `tfsdk:"auth_mode"`
+	APIKey                    types.String `tfsdk:"api_key"`
+	APIKeyHeader              types.String `tfsdk:"api_key_header"`
+	AllowedScopes             types.List   `tfsdk:"allowed_scopes"`
+	TokenExpiry               types.String `tfsdk:"token_expiry"`
+	TokenExpiryWarning        types.String `tfsdk:"token_expiry_warning"`
+	RequestTimeout            types.String `tfsdk:"request_timeout"`
+	MaxConcurrentRequests     types.Int64  `tfsdk:"max_concurrent_requests"`
+	RequestsPerSecond         types.Int64  `tfsdk:"requests_per_second"`
+	BatchRefresh              types.Bool   `tfsdk:"batch_refresh"`
+	AuditLogPath              types.String `tfsdk:"audit_log_path"`
+	StrictValidation          types.Bool   `tfsdk:"strict_validation"`
+	SkipCredentialsValidation types.Bool   `tfsdk:"skip_credentials_validation"`
+	ConfigFile                types.String `tfsdk:"config_file"`
+	Profile                   types.String `tfsdk:"profile"`
+}
+
+// peripheralProviderData is handed to resources, ephemeral resources and data
+// sources on Configure.
+type peripheralProviderData struct {
+	// client is nil while endpoint or token are unknown.
+	client *runrs.ClientWithResponses
+
+	// runners caches the runners listed from runrs if batch_refresh is
+	// enabled, or is nil otherwise.
+	runners *runnerCache
+
+	// auditLog records changes to runners if audit_log_path is set, or is
+	// nil otherwise.
+	auditLog *auditLog
+
+	// tokenExpiry is how long runner tokens are valid after they have been
+	// obtained, or zero if runner tokens don't expire.
+	tokenExpiry time.Duration
+
+	// tokenExpiryUnknown is whether token_expiry is unknown until apply.
+	tokenExpiryUnknown bool
+
+	// tokenExpiryWarning is how long before expiry a runner token is
+	// warned about at plan time.
+	tokenExpiryWarning time.Duration
+
+	// auth authenticates requests to runrs, and has the secret shared with
+	// runrs which JWTs are signed with. It is nil while endpoint or token are
+	// unknown.
+	auth *runrsAuth
+
+	// allowedScopes are the scopes JWTs may be signed for.
+	allowedScopes []string
+
+	// server is the version and capabilities of runrs; the oldest version
+	// the provider supports unless it has been checked.
+	server *runrsInfo
+}
+
+// configUnknown returns whether endpoint or token, the profile they may come
+// from, or other attributes needed to configure the client are unknown, e.g.
+// because runrs runs on a host which is created in the same apply.
+func (m *peripheralProviderModel) configUnknown() bool {
+	if m.Endpoint.IsUnknown() || m.Endpoints.IsUnknown() || m.Token.IsUnknown() ||
+		m.TokenCommand.IsUnknown() || m.OIDC.IsUnknown() || m.AuthMode.IsUnknown() || m.APIKey.IsUnknown() ||
+		m.APIKeyHeader.IsUnknown() || m.AllowedScopes.IsUnknown() || m.ConfigFile.IsUnknown() ||
+		m.Profile.IsUnknown() || m.TokenExpiry.IsUnknown() || m.TokenExpiryWarning.IsUnknown() ||
+		m.RequestTimeout.IsUnknown() || m.AuditLogPath.IsUnknown() {
+		return true
+	}
+
+	elements := append(m.Endpoints.Elements(), m.TokenCommand.Elements()...)
+	elements = append(elements, m.AllowedScopes.Elements()...)
+	for _, attribute := range m.OIDC.Attributes() {
+		elements = append(elements, attribute)
+	}
+
+	for _, element := range elements {
+		if element.IsUnknown() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unknownConfigDiagnostic is the error of operations which need runrs while
+// endpoint or token are unknown.
+func unknownConfigDiagnostic() diag.Diagnostic {
+	return diag.NewErrorDiagnostic(
+		"Unknown Provider Configuration",
+		"The endpoint or token of the provider is unknown, so runrs can't be reached yet. "+
+			"Apply the resources they depend on first, e.g. with -target.",
+	)
+}
+
+// defaultTokenExpiryWarning is used when token_expiry_warning is not set.
+const defaultTokenExpiryWarning = 7 * 24 * time.Hour
+
+// defaultRequestTimeout is used when request_timeout is not set.
+const defaultRequestTimeout = time.Minute
+
+// defaultJWTLifetime is how long JWTs signed by the provider are valid.
+const defaultJWTLifetime = time.Hour
+
+// signJWT signs a JWT for runrs with the shared secret, which is valid from
+// now until expiresAt. The issuer defaults to "peripheral".
+func signJWT(secret string, claims jwt.MapClaims, expiresAt time.Time) (string, error) {
+	signedClaims := jwt.MapClaims{
+		"iss": "peripheral",
+	}
+	for name, value := range claims {
+		signedClaims[name] = value
+	}
+	signedClaims["iat"] = time.Now().Unix()
+	signedClaims["exp"] = expiresAt.Unix()
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, signedClaims).SignedString([]byte(secret))
 }
 
 func (p *peripheralProvider) Metadata(
@@ -54,17 +187,206 @@ func (p *peripheralProvider) Schema(
 	resp.Schema = schema.Schema{
 		Attributes: map[string]schema.Attribute{
 			"endpoint": schema.StringAttribute{
-				MarkdownDescription: "URL for the peripheral API.",
-				Required:            true,
+				MarkdownDescription: "URL for the peripheral API. Exactly one of `endpoint` or " +
+					"`endpoints` must be set, unless the profile sets one.",
+				Optional: true,
+			},
+			"endpoints": schema.ListAttribute{
+				MarkdownDescription: "URLs of peripheral API hosts which share state, in order of " +
+					"preference. Reads fail over to the next host on connection errors or " +
+					"`ConnectionFailed`, and changes only if the host can't be connected to. All requests " +
+					"of a resource operation go to the same host.",
+				ElementType: types.StringType,
+				Optional:    true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+					listvalidator.UniqueValues(),
+				},
 			},
 			"token": schema.StringAttribute{
-				MarkdownDescription: "Access token for peripheral.",
-				Required:            true,
+				MarkdownDescription: "Access token for peripheral, which JWTs are signed with in " +
+					"the `jwt` auth mode. Must be set unless `token_command` is set or the profile " +
+					"sets it.",
+				Optional: true,
+			},
+			"token_command": schema.ListAttribute{
+				MarkdownDescription: "Command which prints the access token for peripheral, as a " +
+					"list of the program and its arguments, e.g. to read it from a password manager. " +
+					"It prints either the secret JWTs are signed with, or a JSON object with either " +
+					"`secret` or a ready-made `bearer_token`, and optionally an RFC 3339 `expires_at`, " +
+					"after which the command is run again. Only for the `jwt` auth mode; conflicts " +
+					"with `token`.",
+				ElementType: types.StringType,
+				Optional:    true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+			},
+			"oidc": schema.SingleNestedAttribute{
+				MarkdownDescription: "Authenticate with an OIDC ID token, e.g. from the `id_tokens` of " +
+					"a GitLab CI job, in the `oidc` auth mode. The ID token is read again when it " +
+					"expires.",
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"token_file": schema.StringAttribute{
+						MarkdownDescription: "Path of a file which holds the ID token. Conflicts " +
+							"with `token_env`.",
+						Optional: true,
+						Validators: []validator.String{
+							stringvalidator.ExactlyOneOf(
+								path.MatchRelative().AtParent().AtName("token_env"),
+							),
+						},
+					},
+					"token_env": schema.StringAttribute{
+						MarkdownDescription: "Environment variable which holds the ID token. " +
+							"Conflicts with `token_file`.",
+						Optional: true,
+					},
+					"audience": schema.StringAttribute{
+						MarkdownDescription: "Audience the ID token must be for, which is also " +
+							"requested in the token exchange.",
+						Optional: true,
+					},
+					"exchange_path": schema.StringAttribute{
+						MarkdownDescription: "Path of the token exchange endpoint of runrs, " +
+							"relative to the endpoint, e.g. `oauth/token`. The ID token is exchanged " +
+							"there for an access token of runrs as in RFC 8693; without it, the ID " +
+							"token is sent as the bearer token.",
+						Optional: true,
+					},
+				},
+			},
+			"auth_mode": schema.StringAttribute{
+				MarkdownDescription: "How requests to the peripheral API are authenticated: `jwt` " +
+					"signs JWTs with `token` or the secret of `token_command`, `oidc` uses the ID token " +
+					"of `oidc`, `api_key` sends `api_key` in a header, and `none` sends no credentials, " +
+					"e.g. for local development. Only the attributes of the auth mode may be set; " +
+					"defaults to `oidc` if `oidc` is set, or `jwt`.",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(authModes...),
+				},
+			},
+			"api_key": schema.StringAttribute{
+				MarkdownDescription: "Static API key sent with every request in the `api_key` auth " +
+					"mode, e.g. for runrs behind a reverse proxy which expects one.",
+				Optional:  true,
+				Sensitive: true,
+			},
+			"api_key_header": schema.StringAttribute{
+				MarkdownDescription: "Header `api_key` is sent in; defaults to `X-API-Key`.",
+				Optional:            true,
+			},
+			"allowed_scopes": schema.ListAttribute{
+				MarkdownDescription: "Scopes JWTs may be signed for, out of `gitlab-runners:read` and " +
+					"`gitlab-runners:write`; defaults to both. Each request is signed for the scope of " +
+					"its operation in the `scope` claim, and requests for other scopes fail before they " +
+					"are sent, e.g. `[\"gitlab-runners:read\"]` for plan-only pipelines. Must include " +
+					"`gitlab-runners:read`.",
+				ElementType: types.StringType,
+				Optional:    true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+					listvalidator.UniqueValues(),
+					listvalidator.ValueStringsAre(stringvalidator.OneOf(allScopes...)),
+				},
+			},
+			"config_file": schema.StringAttribute{
+				MarkdownDescription: "Path of a YAML or TOML file of named profiles, which set " +
+					"`endpoint`, `endpoints` and `token` where they aren't set in the provider " +
+					"block; defaults to `~/.config/peripheral/config`.",
+				Optional: true,
+			},
+			"profile": schema.StringAttribute{
+				MarkdownDescription: "Profile of the config file to use; defaults to the " +
+					"`PERIPHERAL_PROFILE` environment variable, or `default`.",
+				Optional: true,
+			},
+			"token_expiry": schema.StringAttribute{
+				MarkdownDescription: "How long GitLab runner tokens are valid after they have been " +
+					"obtained, e.g. `2160h`. When set, runners get a `token_expires_at` attribute, " +
+					"and plans fail for runners with expired tokens.",
+				Optional: true,
+				Validators: []validator.String{
+					isDuration(),
+				},
+			},
+			"token_expiry_warning": schema.StringAttribute{
+				MarkdownDescription: "How long before expiry plans warn about GitLab runner tokens; " +
+					"defaults to `168h`.",
+				Optional: true,
+				Validators: []validator.String{
+					isDuration(),
+				},
+			},
+			"request_timeout": schema.StringAttribute{
+				MarkdownDescription: "How long to wait for each response of the peripheral API; " +
+					"defaults to `1m`.",
+				Optional: true,
+				Validators: []validator.String{
+					isDuration(),
+				},
+			},
+			"max_concurrent_requests": schema.Int64Attribute{
+				MarkdownDescription: "How many requests to the peripheral API may be in flight at a " +
+					"time, across all resources; defaults to `10`.",
+				Optional: true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"requests_per_second": schema.Int64Attribute{
+				MarkdownDescription: "How many requests to the peripheral API may start per second, " +
+					"across all resources; unlimited by default. Requests back off when the " +
+					"peripheral API answers with `429 Too Many Requests` either way.",
+				Optional: true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"batch_refresh": schema.BoolAttribute{
+				MarkdownDescription: "Refresh all runners from a single list of the peripheral API " +
+					"instead of reading each of them, which speeds up plans of large fleets; " +
+					"defaults to `false`.",
+				Optional: true,
+			},
+			"audit_log_path": schema.StringAttribute{
+				MarkdownDescription: "Path of a file which every create, update and delete of a runner " +
+					"is appended to as a JSON line, with the attributes that changed, the outcome " +
+					"and the error type of runrs. Tokens are redacted.",
+				Optional: true,
+			},
+			"skip_credentials_validation": schema.BoolAttribute{
+				MarkdownDescription: "Skip checking `endpoint` and `token`, and the version of runrs, " +
+					"when the provider is configured, e.g. for offline plans; defaults to `false`.",
+				Optional: true,
+			},
+			"strict_validation": schema.BoolAttribute{
+				MarkdownDescription: "Validate every request to and response of the peripheral API " +
+					"against the OpenAPI spec the provider was built with, and warn about mismatches; " +
+					"defaults to the `PERIPHERAL_STRICT_VALIDATION` environment variable, or `false`.",
+				Optional: true,
 			},
 		},
 	}
 }
 
+func (p *peripheralProvider) ConfigValidators(ctx context.Context) []provider.ConfigValidator {
+	return []provider.ConfigValidator{
+		// Either may also come from the profile, which is checked in Configure.
+		providervalidator.Conflicting(
+			path.MatchRoot("endpoint"),
+			path.MatchRoot("endpoints"),
+		),
+		providervalidator.Conflicting(
+			path.MatchRoot("token"),
+			path.MatchRoot("token_command"),
+		),
+		authModeValidator{},
+	}
+}
+
 func (p *peripheralProvider) Configure(
 	ctx context.Context,
 	req provider.ConfigureRequest,
@@ -77,32 +399,308 @@ func (p *peripheralProvider) Configure(
 		return
 	}
 
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"iss": "peripheral",
-		"exp": time.Now().Add(time.Hour).Unix(),
-	})
+	providerData := peripheralProviderData{
+		tokenExpiryWarning: defaultTokenExpiryWarning,
+		server:             newRunrsInfo(minRunrsVersion),
+	}
+
+	// Unknown attributes are left at their defaults while the configuration
+	// is unknown, and parsed once Terraform configures the provider again.
+	providerData.tokenExpiryUnknown = data.TokenExpiry.IsUnknown()
+
+	if !data.TokenExpiry.IsNull() && !data.TokenExpiry.IsUnknown() {
+		tokenExpiry, err := time.ParseDuration(data.TokenExpiry.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("token_expiry"),
+				"Invalid Duration",
+				fmt.Sprintf("Unable to parse token_expiry: %s", err),
+			)
+			return
+		}
+		providerData.tokenExpiry = tokenExpiry
+	}
+
+	if !data.TokenExpiryWarning.IsNull() && !data.TokenExpiryWarning.IsUnknown() {
+		tokenExpiryWarning, err := time.ParseDuration(data.TokenExpiryWarning.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("token_expiry_warning"),
+				"Invalid Duration",
+				fmt.Sprintf("Unable to parse token_expiry_warning: %s", err),
+			)
+			return
+		}
+		providerData.tokenExpiryWarning = tokenExpiryWarning
+	}
+
+	if !data.AuditLogPath.IsNull() && !data.AuditLogPath.IsUnknown() {
+		auditLog, err := newAuditLog(data.AuditLogPath.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("audit_log_path"),
+				"Invalid Audit Log Path",
+				fmt.Sprintf("Unable to open audit log: %s", err),
+			)
+			return
+		}
+		providerData.auditLog = auditLog
+	}
+
+	requestTimeout := defaultRequestTimeout
+	if !data.RequestTimeout.IsNull() && !data.RequestTimeout.IsUnknown() {
+		var err error
+		requestTimeout, err = time.ParseDuration(data.RequestTimeout.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("request_timeout"),
+				"Invalid Duration",
+				fmt.Sprintf("Unable to parse request_timeout: %s", err),
+			)
+			return
+		}
+	}
+
+	if data.configUnknown() {
+		// Terraform defers all runners until the configuration is known, if it
+		// supports deferred actions.
+		if req.ClientCapabilities.DeferralAllowed {
+			resp.Deferred = &provider.Deferred{Reason: provider.DeferredReasonProviderConfigUnknown}
+			return
+		}
+
+		// Otherwise runners are planned without runrs, and Terraform configures
+		// the provider again with the known configuration before applying.
+		tflog.Debug(ctx, "endpoint or token unknown, planning without runrs")
 
-	encodedToken, err := jwtToken.SignedString([]byte(data.Token.ValueString()))
+		resp.DataSourceData = &providerData
+		resp.ResourceData = &providerData
+		resp.EphemeralResourceData = &providerData
+		return
+	}
+
+	profileName := data.Profile.ValueString()
+	if data.Profile.IsNull() {
+		profileName = os.Getenv(profileEnvVar)
+	}
+
+	profile, err := loadConfigProfile(data.ConfigFile.ValueString(), profileName)
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Token Encoding Error",
-			fmt.Sprintf("Failed to encode JWT token: %s", err),
+		resp.Diagnostics.AddAttributeError(
+			path.Root("config_file"),
+			"Invalid Config File",
+			fmt.Sprintf("Unable to read profile: %s", err),
+		)
+		return
+	}
+
+	var endpoints []string
+	switch {
+	case !data.Endpoints.IsNull():
+		resp.Diagnostics.Append(data.Endpoints.ElementsAs(ctx, &endpoints, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	case !data.Endpoint.IsNull():
+		endpoints = []string{data.Endpoint.ValueString()}
+	case profile != nil:
+		endpoints = profile.endpoints()
+	}
+	if len(endpoints) == 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("endpoint"),
+			"Missing Endpoint",
+			"Set endpoint or endpoints, or use a profile which sets them.",
 		)
 		return
 	}
 
-	bearerToken, err := securityprovider.NewSecurityProviderBearerToken(encodedToken)
+	var command *tokenCommand
+	if !data.TokenCommand.IsNull() {
+		var argv []string
+		resp.Diagnostics.Append(data.TokenCommand.ElementsAs(ctx, &argv, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		command = newTokenCommand(argv)
+	}
+
+	token := data.Token.ValueString()
+	mode := data.authMode()
+	if mode == authModeJWT && data.Token.IsNull() && command == nil {
+		if profile != nil {
+			token, err = profile.token()
+			if err != nil {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("profile"),
+					"Invalid Profile",
+					fmt.Sprintf("Unable to read token: %s", err),
+				)
+				return
+			}
+		}
+		if token == "" {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("token"),
+				"Missing Token",
+				"Set token or token_command, or use a profile which sets the token, or set "+
+					"auth_mode for another way to authenticate.",
+			)
+			return
+		}
+	}
+
+	claims := jwt.MapClaims{}
+	httpClient := &http.Client{Timeout: requestTimeout}
+	if profile != nil {
+		for name, value := range profile.Claims {
+			claims[name] = value
+		}
+
+		tlsConfig, err := profile.tlsConfig()
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("profile"),
+				"Invalid Profile",
+				fmt.Sprintf("Unable to set up TLS: %s", err),
+			)
+			return
+		}
+		if transport, ok := http.DefaultTransport.(*http.Transport); ok && tlsConfig != nil {
+			transport = transport.Clone()
+			transport.TLSClientConfig = tlsConfig
+			httpClient.Transport = transport
+		}
+	}
+
+	allowedScopes := allScopes
+	if !data.AllowedScopes.IsNull() {
+		allowedScopes = nil
+		resp.Diagnostics.Append(data.AllowedScopes.ElementsAs(ctx, &allowedScopes, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		// Refreshing runners needs to read them.
+		if !slices.Contains(allowedScopes, scopeRead) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("allowed_scopes"),
+				"Missing Scope",
+				fmt.Sprintf("allowed_scopes must include %s, which reading runners needs.", scopeRead),
+			)
+			return
+		}
+	}
+
+	maxConcurrentRequests := int64(defaultMaxConcurrentRequests)
+	if !data.MaxConcurrentRequests.IsNull() {
+		maxConcurrentRequests = data.MaxConcurrentRequests.ValueInt64()
+	}
+
+	credential := staticCredential(token)
+	var identity *oidcIdentity
+	switch mode {
+	case authModeJWT:
+		if command != nil {
+			credential = command.credential
+		}
+	case authModeOIDC:
+		var oidc peripheralProviderOIDCModel
+		resp.Diagnostics.Append(data.OIDC.As(ctx, &oidc, basetypes.ObjectAsOptions{})...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		identity, err = newOIDCIdentity(oidc, httpClient, endpoints)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("oidc"),
+				"Invalid OIDC Configuration",
+				fmt.Sprintf("Unable to set up OIDC: %s", err),
+			)
+			return
+		}
+		credential = identity.credential
+	default:
+		credential = noCredential(mode)
+	}
+
+	auth := newRunrsAuth(credential, claims)
+
+	// Requests are authenticated with JWTs or bearer tokens of auth, unless
+	// the auth mode doesn't sign them.
+	intercept := runrs.RequestEditorFn(auth.Intercept)
+	switch mode {
+	case authModeAPIKey:
+		header := defaultAPIKeyHeader
+		if !data.APIKeyHeader.IsNull() {
+			header = data.APIKeyHeader.ValueString()
+		}
+
+		apiKey, err := securityprovider.NewSecurityProviderApiKey("header", header, data.APIKey.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Auth Setup Error",
+				fmt.Sprintf("Failed to set up auth: %s", err),
+			)
+			return
+		}
+		intercept = apiKey.Intercept
+	case authModeNone:
+		intercept = nil
+	}
+
+	// JWTs are masked in logs with the Authorization header.
+	failover, err := newEndpointFailover(newRequestLogger(httpClient, token, data.APIKey.ValueString()), endpoints)
 	if err != nil {
 		resp.Diagnostics.AddError(
-			"Auth Setup Error",
-			fmt.Sprintf("Failed to set up auth: %s", err),
+			"Client Setup Error",
+			fmt.Sprintf("Failed to set up client: %s", err),
 		)
 		return
 	}
 
+	strictValidation := data.StrictValidation.ValueBool()
+	if data.StrictValidation.IsNull() {
+		if value, ok := os.LookupEnv(strictValidationEnvVar); ok {
+			strictValidation, err = strconv.ParseBool(value)
+			if err != nil {
+				resp.Diagnostics.AddError(
+					"Invalid Environment Variable",
+					fmt.Sprintf("Unable to parse %s: %s", strictValidationEnvVar, err),
+				)
+				return
+			}
+		}
+	}
+
+	limiter := newRequestLimiter(failover, maxConcurrentRequests, data.RequestsPerSecond.ValueInt64())
+
+	var doer runrs.HttpRequestDoer = limiter
+	if strictValidation {
+		doer, err = newSpecValidator(doer, endpoints)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Client Setup Error",
+				fmt.Sprintf("Failed to set up strict validation: %s", err),
+			)
+			return
+		}
+	}
+
+	// Scopes are recorded before requests are signed for them.
+	clientOptions := []runrs.ClientOption{runrs.WithRequestEditorFn(scopeRequest(allowedScopes))}
+	if intercept != nil {
+		clientOptions = append(clientOptions, runrs.WithRequestEditorFn(intercept))
+	}
+
 	client, err := runrs.NewClientWithResponses(
-		data.Endpoint.ValueString(),
-		runrs.WithRequestEditorFn(bearerToken.Intercept),
+		endpoints[0],
+		append(
+			clientOptions,
+			runrs.WithRequestEditorFn(traceRequest),
+			runrs.WithHTTPClient(newRequestTracer(doer)),
+		)...,
 	)
 	if err != nil {
 		resp.Diagnostics.AddError(
@@ -112,8 +710,56 @@ func (p *peripheralProvider) Configure(
 		return
 	}
 
-	resp.DataSourceData = client
-	resp.ResourceData = client
+	// Offline plans can skip talking to runrs here, at the cost of finding
+	// out about a wrong endpoint or token from every resource instead.
+	if !data.SkipCredentialsValidation.ValueBool() {
+		if command != nil {
+			if _, err := command.credential(ctx); err != nil {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("token_command"),
+					"Token Command Failed",
+					fmt.Sprintf("Unable to obtain the token: %s", err),
+				)
+				return
+			}
+		}
+
+		if identity != nil {
+			if _, err := identity.credential(ctx); err != nil {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("oidc"),
+					"OIDC Authentication Failed",
+					fmt.Sprintf("Unable to obtain a token from the ID token: %s", err),
+				)
+				return
+			}
+		}
+
+		resp.Diagnostics.Append(validateCredentials(ctx, client, endpoints, data.credentialAttribute())...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		// The spec is requested from the first endpoint, and the limiter
+		// sends it to the others if the first is unreachable, like any other request.
+		server, diags := checkRunrsVersion(ctx, limiter, endpoints[0])
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		providerData.server = server
+	}
+
+	providerData.client = client
+	if data.BatchRefresh.ValueBool() {
+		providerData.runners = newRunnerCache(client)
+	}
+	providerData.auth = auth
+	providerData.allowedScopes = allowedScopes
+
+	resp.DataSourceData = &providerData
+	resp.ResourceData = &providerData
+	resp.EphemeralResourceData = &providerData
 }
 
 func (p *peripheralProvider) Resources(ctx context.Context) []func() resource.Resource {
@@ -122,6 +768,13 @@ func (p *peripheralProvider) Resources(ctx context.Context) []func() resource.Re
 	}
 }
 
+func (p *peripheralProvider) EphemeralResources(ctx context.Context) []func() ephemeral.EphemeralResource {
+	return []func() ephemeral.EphemeralResource{
+		NewGitLabRunnerTokenEphemeralResource,
+		NewAccessTokenEphemeralResource,
+	}
+}
+
 func (p *peripheralProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
 	return []func() datasource.DataSource{}
 }
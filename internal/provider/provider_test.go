@@ -4,18 +4,58 @@
 package provider
 
 import (
+	"context"
+	"fmt"
+	"os"
 	"testing"
 
+	fwprovider "github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+
+	"terraform-provider-peripheral/internal/runrstest"
+)
+
+// testRunrsURL and testRunrsSecret point the tests at runrs: an in-memory
+// runrs by default, or the one at RUNRS_ENDPOINT with the secret
+// RUNRS_SECRET.
+var (
+	testRunrsURL    string
+	testRunrsSecret = "warblgarbl"
 )
 
-const providerConfig = `
+// providerConfig configures the provider for testRunrsURL.
+var providerConfig string
+
+func TestMain(m *testing.M) {
+	var fake *runrstest.Server
+
+	if endpoint := os.Getenv("RUNRS_ENDPOINT"); endpoint != "" {
+		testRunrsURL = endpoint
+		if secret := os.Getenv("RUNRS_SECRET"); secret != "" {
+			testRunrsSecret = secret
+		}
+	} else {
+		fake = runrstest.NewServer(testRunrsSecret)
+		testRunrsURL = fake.URL
+	}
+
+	providerConfig = fmt.Sprintf(`
 	provider "peripheral" {
-		endpoint = "http://0.0.0.0:3000"
-		token = "warblgarbl"
+		endpoint = %q
+		token = %q
 	}
-`
+`, testRunrsURL, testRunrsSecret)
+
+	code := m.Run()
+
+	if fake != nil {
+		fake.Close()
+	}
+
+	os.Exit(code)
+}
 
 // testAccProtoV6ProviderFactories are used to instantiate a provider during
 // acceptance testing. The factory function will be invoked for every Terraform
@@ -30,3 +70,177 @@ func testAccPreCheck(t *testing.T) {
 	// about the appropriate environment variables being set are common to see in a pre-check
 	// function.
 }
+
+// testProtocol6ProviderServer returns a configured provider server.
+func testProtocol6ProviderServer(t *testing.T) tfprotov6.ProviderServer {
+	t.Helper()
+
+	server, diags := testConfigureProvider(t, nil)
+	for _, d := range diags {
+		if d.Severity == tfprotov6.DiagnosticSeverityError {
+			t.Fatalf("unable to configure provider: %s: %s", d.Summary, d.Detail)
+		}
+	}
+
+	return server
+}
+
+// testConfigureProvider returns a provider server configured for the test
+// runrs, with config overriding the provider configuration, and the
+// diagnostics of configuring it.
+func testConfigureProvider(
+	t *testing.T,
+	config map[string]tftypes.Value,
+) (tfprotov6.ProviderServer, []*tfprotov6.Diagnostic) {
+	t.Helper()
+
+	return testConfigureProviderWithCapabilities(t, config, nil)
+}
+
+// testConfigureProviderWithCapabilities is testConfigureProvider for a
+// Terraform with the given capabilities.
+func testConfigureProviderWithCapabilities(
+	t *testing.T,
+	config map[string]tftypes.Value,
+	capabilities *tfprotov6.ConfigureProviderClientCapabilities,
+) (tfprotov6.ProviderServer, []*tfprotov6.Diagnostic) {
+	t.Helper()
+
+	server, err := providerserver.NewProtocol6WithError(New("test")())()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := server.ConfigureProvider(context.Background(), &tfprotov6.ConfigureProviderRequest{
+		Config:             testProviderConfig(t, config),
+		ClientCapabilities: capabilities,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return server, resp.Diagnostics
+}
+
+// testProviderConfig returns the provider configuration with the endpoint
+// and token of the runrs the tests run against, overridden by config.
+func testProviderConfig(t *testing.T, config map[string]tftypes.Value) *tfprotov6.DynamicValue {
+	t.Helper()
+
+	ctx := context.Background()
+
+	var schemaResp fwprovider.SchemaResponse
+	New("test")().Schema(ctx, fwprovider.SchemaRequest{}, &schemaResp)
+	configType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	values := map[string]tftypes.Value{}
+	for name, typ := range configType.AttributeTypes {
+		values[name] = tftypes.NewValue(typ, nil)
+	}
+	values["endpoint"] = tftypes.NewValue(tftypes.String, testRunrsURL)
+	values["token"] = tftypes.NewValue(tftypes.String, testRunrsSecret)
+	for name, value := range config {
+		values[name] = value
+	}
+
+	configValue, err := tfprotov6.NewDynamicValue(configType, tftypes.NewValue(configType, values))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &configValue
+}
+
+func TestProviderUnknownConfig(t *testing.T) {
+	ctx := context.Background()
+
+	stateType := testRunnerResourceSchemaState(t).Schema.Type().TerraformType(ctx).(tftypes.Object)
+	values := map[string]tftypes.Value{}
+	for name, typ := range stateType.AttributeTypes {
+		values[name] = tftypes.NewValue(typ, nil)
+	}
+	values["uuid"] = tftypes.NewValue(tftypes.String, testCachedRunnerUuid)
+	values["id"] = tftypes.NewValue(tftypes.Number, 42)
+	values["url"] = tftypes.NewValue(tftypes.String, "https://gitlab.com/")
+	values["token"] = tftypes.NewValue(tftypes.String, "glrt-0123456789-abcdefXYZ")
+	values["docker_image"] = tftypes.NewValue(tftypes.String, "alpine:latest")
+
+	state, err := tfprotov6.NewDynamicValue(stateType, tftypes.NewValue(stateType, values))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		attribute        string
+		deferralAllowed  bool
+		expectedDeferred bool
+	}{
+		"deferral allowed": {
+			attribute:        "endpoint",
+			deferralAllowed:  true,
+			expectedDeferred: true,
+		},
+		"deferral not allowed": {
+			attribute: "endpoint",
+		},
+		"token_expiry": {
+			attribute:        "token_expiry",
+			deferralAllowed:  true,
+			expectedDeferred: true,
+		},
+		"token_expiry deferral not allowed": {
+			attribute: "token_expiry",
+		},
+		"token_expiry_warning": {
+			attribute: "token_expiry_warning",
+		},
+		"request_timeout": {
+			attribute: "request_timeout",
+		},
+		"audit_log_path": {
+			attribute: "audit_log_path",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			server, diags := testConfigureProviderWithCapabilities(
+				t,
+				map[string]tftypes.Value{
+					testCase.attribute: tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+				},
+				&tfprotov6.ConfigureProviderClientCapabilities{DeferralAllowed: testCase.deferralAllowed},
+			)
+			if len(diags) > 0 {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+
+			resp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+				TypeName:           "peripheral_gitlab_runner",
+				CurrentState:       &state,
+				ClientCapabilities: &tfprotov6.ReadResourceClientCapabilities{DeferralAllowed: testCase.deferralAllowed},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Diagnostics) > 0 {
+				t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+			}
+
+			if testCase.expectedDeferred {
+				if resp.Deferred == nil || resp.Deferred.Reason != tfprotov6.DeferredReasonProviderConfigUnknown {
+					t.Errorf("expected read to be deferred, got %v", resp.Deferred)
+				}
+				return
+			}
+
+			newState, err := resp.NewState.Unmarshal(stateType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !newState.Equal(tftypes.NewValue(stateType, values)) {
+				t.Errorf("expected state to be kept, got %s", newState)
+			}
+		})
+	}
+}
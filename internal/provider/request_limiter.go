@@ -0,0 +1,185 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+// defaultMaxConcurrentRequests is used when max_concurrent_requests is not
+// set.
+const defaultMaxConcurrentRequests = 10
+
+// maxTooManyRequestsRetries is how often a request is retried after runrs
+// answered with 429 Too Many Requests.
+const maxTooManyRequestsRetries = 5
+
+// tooManyRequestsBackoff is how long requests back off after the first 429
+// Too Many Requests, unless runrs sends Retry-After. It doubles on every
+// retry, up to maxTooManyRequestsBackoff.
+var (
+	tooManyRequestsBackoff    = time.Second
+	maxTooManyRequestsBackoff = 30 * time.Second
+)
+
+// requestLimiter limits the requests of all resources to runrs, so that
+// large fleets don't overwhelm it.
+type requestLimiter struct {
+	doer runrs.HttpRequestDoer
+
+	// slots holds a value for every request in flight, or is nil if the
+	// number of requests in flight isn't limited.
+	slots chan struct{}
+
+	// interval is the minimum time between the start of two requests, or
+	// zero if the request rate isn't limited.
+	interval time.Duration
+
+	// next is when the next request may start, which is pushed back when
+	// runrs answers with 429 Too Many Requests.
+	mu   sync.Mutex
+	next time.Time
+}
+
+// newRequestLimiter returns a requestLimiter which sends at most
+// maxConcurrent requests at a time, and at most perSecond requests per
+// second, through doer. Zero doesn't limit either.
+func newRequestLimiter(doer runrs.HttpRequestDoer, maxConcurrent int64, perSecond int64) *requestLimiter {
+	l := &requestLimiter{doer: doer}
+
+	if maxConcurrent > 0 {
+		l.slots = make(chan struct{}, maxConcurrent)
+	}
+	if perSecond > 0 {
+		l.interval = time.Second / time.Duration(perSecond)
+	}
+
+	return l
+}
+
+func (l *requestLimiter) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	backoff := tooManyRequestsBackoff
+
+	for retry := 0; ; retry++ {
+		resp, err := l.do(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || retry == maxTooManyRequestsRetries {
+			return resp, err
+		}
+
+		wait := backoff
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			wait = time.Duration(seconds) * time.Second
+		}
+		resp.Body.Close()
+
+		tflog.Debug(ctx, fmt.Sprintf("runrs is rate limiting, backing off for %s", wait), map[string]interface{}{
+			"method": req.Method,
+			"path":   req.URL.Path,
+			"retry":  retry + 1,
+		})
+
+		l.backOff(wait)
+		backoff = min(2*backoff, maxTooManyRequestsBackoff)
+
+		if req.GetBody != nil {
+			req.Body, err = req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// do sends the request once there is a slot for it, and the rate limit
+// allows it. The slot is held until the response body is closed, since the
+// response is still being read from runrs until then.
+func (l *requestLimiter) do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	release := func() {}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+			var once sync.Once
+			release = func() { once.Do(func() { <-l.slots }) }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if err := l.wait(ctx); err != nil {
+		release()
+		return nil, err
+	}
+
+	resp, err := l.doer.Do(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	resp.Body = &slotBody{ReadCloser: resp.Body, release: release}
+
+	return resp, nil
+}
+
+// slotBody is the body of a response which holds a slot of the
+// requestLimiter, and releases it when it is closed.
+type slotBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *slotBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
+
+// wait blocks until the next request may start.
+func (l *requestLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// backOff holds back all requests for the given time.
+func (l *requestLimiter) backOff(wait time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if next := time.Now().Add(wait); next.After(l.next) {
+		l.next = next
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestLimiterConcurrency(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}))
+	t.Cleanup(server.Close)
+
+	limiter := newRequestLimiter(http.DefaultClient, 2, 0)
+
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+			resp, err := limiter.Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if n := maxInFlight.Load(); n != 2 {
+		t.Errorf("expected 2 requests in flight, got %d", n)
+	}
+}
+
+func TestRequestLimiterBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(server.Close)
+
+	limiter := newRequestLimiter(http.DefaultClient, 1, 0)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	first, err := limiter.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The slot is held while the body of the first response is unread.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if _, err := limiter.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the second request to wait for a slot, got %v", err)
+	}
+
+	if _, err := io.ReadAll(first.Body); err != nil {
+		t.Fatal(err)
+	}
+	first.Body.Close()
+
+	req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	second, err := limiter.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.Body.Close()
+
+	// Closing the body again doesn't release another slot.
+	first.Body.Close()
+	if n := len(limiter.slots); n != 0 {
+		t.Errorf("expected no slots in use, got %d", n)
+	}
+}
+
+func TestRequestLimiterRate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	limiter := newRequestLimiter(http.DefaultClient, 0, 20)
+
+	start := time.Now()
+	for range 5 {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		resp, err := limiter.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	// The first request starts right away, and the others 50ms apart.
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("expected 5 requests to take at least 200ms, took %s", elapsed)
+	}
+}
+
+func TestRequestLimiterTooManyRequests(t *testing.T) {
+	backoff := tooManyRequestsBackoff
+	tooManyRequestsBackoff = 10 * time.Millisecond
+	t.Cleanup(func() { tooManyRequestsBackoff = backoff })
+
+	testCases := map[string]struct {
+		tooManyRequests int
+		expectedStatus  int
+		expectedBodies  int
+	}{
+		"backs off": {
+			tooManyRequests: 2,
+			expectedStatus:  http.StatusOK,
+			expectedBodies:  3,
+		},
+		"gives up": {
+			tooManyRequests: maxTooManyRequestsRetries + 1,
+			expectedStatus:  http.StatusTooManyRequests,
+			expectedBodies:  maxTooManyRequestsRetries + 1,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var bodies []string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				bodies = append(bodies, string(body))
+
+				if len(bodies) <= testCase.tooManyRequests {
+					w.WriteHeader(http.StatusTooManyRequests)
+				}
+			}))
+			t.Cleanup(server.Close)
+
+			limiter := newRequestLimiter(http.DefaultClient, 1, 0)
+
+			req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"name": "runner"}`))
+			resp, err := limiter.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != testCase.expectedStatus {
+				t.Errorf("expected status %d, got %d", testCase.expectedStatus, resp.StatusCode)
+			}
+			if len(bodies) != testCase.expectedBodies {
+				t.Errorf("expected %d requests, got %d", testCase.expectedBodies, len(bodies))
+			}
+			for _, body := range bodies {
+				if body != `{"name": "runner"}` {
+					t.Errorf("expected body to be sent on every retry, got %q", body)
+				}
+			}
+		})
+	}
+}
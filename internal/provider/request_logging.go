@@ -0,0 +1,106 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+// runrsLogSubsystem is the tflog subsystem of requests to runrs. Its level is
+// set with TF_LOG_PROVIDER_PERIPHERAL_RUNRS, or else TF_LOG_PROVIDER.
+const runrsLogSubsystem = "runrs"
+
+// runnerTokenRegexp matches runner tokens in request and response bodies.
+var runnerTokenRegexp = regexp.MustCompile(`"token"\s*:\s*"[^"]*"`)
+
+// requestLogger logs every request to runrs and its response, with secrets
+// masked.
+type requestLogger struct {
+	doer runrs.HttpRequestDoer
+
+	// secrets are masked wherever they show up in logs.
+	secrets []string
+}
+
+// newRequestLogger returns a requestLogger which sends requests through doer,
+// and masks the given secrets.
+func newRequestLogger(doer runrs.HttpRequestDoer, secrets ...string) *requestLogger {
+	l := &requestLogger{doer: doer}
+
+	// Masking empty strings would mask everything in between.
+	for _, secret := range secrets {
+		if secret != "" {
+			l.secrets = append(l.secrets, secret)
+		}
+	}
+
+	return l
+}
+
+func (l *requestLogger) Do(req *http.Request) (*http.Response, error) {
+	ctx := tflog.NewSubsystem(
+		req.Context(),
+		runrsLogSubsystem,
+		tflog.WithLevelFromEnv("TF_LOG_PROVIDER_PERIPHERAL_RUNRS"),
+	)
+	ctx = tflog.SubsystemMaskFieldValuesWithFieldKeys(ctx, runrsLogSubsystem, "authorization")
+	ctx = tflog.SubsystemMaskAllFieldValuesRegexes(ctx, runrsLogSubsystem, runnerTokenRegexp)
+	ctx = tflog.SubsystemMaskAllFieldValuesStrings(ctx, runrsLogSubsystem, l.secrets...)
+	ctx = tflog.SubsystemMaskMessageStrings(ctx, runrsLogSubsystem, l.secrets...)
+
+	fields := map[string]interface{}{
+		"method":        req.Method,
+		"url":           req.URL.String(),
+		"authorization": req.Header.Get("Authorization"),
+	}
+
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			requestBody, _ := io.ReadAll(body)
+			body.Close()
+			tflog.SubsystemTrace(ctx, runrsLogSubsystem, "sending request body", map[string]interface{}{
+				"method": req.Method,
+				"url":    req.URL.String(),
+				"body":   string(requestBody),
+			})
+		}
+	}
+
+	start := time.Now()
+	resp, err := l.doer.Do(req)
+	fields["latency"] = time.Since(start).String()
+
+	if err != nil {
+		fields["error"] = err.Error()
+		tflog.SubsystemDebug(ctx, runrsLogSubsystem, "request failed", fields)
+		return nil, err
+	}
+
+	fields["status"] = resp.StatusCode
+	tflog.SubsystemDebug(ctx, runrsLogSubsystem, "received response", fields)
+
+	responseBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	tflog.SubsystemTrace(ctx, runrsLogSubsystem, "received response body", map[string]interface{}{
+		"method": req.Method,
+		"url":    req.URL.String(),
+		"status": resp.StatusCode,
+		"body":   string(responseBody),
+	})
+
+	return resp, nil
+}
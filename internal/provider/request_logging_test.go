@@ -0,0 +1,101 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+func TestRequestLogger(t *testing.T) {
+	const secret = "warblgarbl"
+	const runnerToken = "glrt-test1_abcdefXYZ"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{
+			"uuid": "` + testCachedRunnerUuid + `",
+			"id": 1,
+			"url": "https://gitlab.com",
+			"token": "` + runnerToken + `",
+			"docker_image": "alpine:latest",
+			"name": "leaked ` + secret + `"
+		}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := runrs.NewClientWithResponses(
+		server.URL,
+		runrs.WithHTTPClient(newRequestLogger(http.DefaultClient, secret, "")),
+		runrs.WithRequestEditorFn(func(ctx context.Context, req *http.Request) error {
+			req.Header.Set("Authorization", "Bearer some.signed.jwt")
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+
+	apiResp, err := client.CreateWithResponse(ctx, runrs.GitLabRunner{
+		Id:          1,
+		Url:         "https://gitlab.com",
+		Token:       runnerToken,
+		DockerImage: "alpine:latest",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The response body is still read by the client after it was logged.
+	if apiResp.JSON201 == nil || apiResp.JSON201.Token != runnerToken {
+		t.Errorf("expected response to be parsed, got %s", apiResp.Body)
+	}
+
+	logs := output.String()
+
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var messages []string
+	for _, entry := range entries {
+		messages = append(messages, entry["@message"].(string))
+
+		if entry["@module"] != "provider.runrs" {
+			t.Errorf("expected runrs subsystem, got %v", entry["@module"])
+		}
+		if authorization, ok := entry["authorization"]; ok && authorization != "***" {
+			t.Errorf("expected authorization to be masked, got %v", authorization)
+		}
+	}
+
+	expectedMessages := "sending request body, received response, received response body"
+	if got := strings.Join(messages, ", "); got != expectedMessages {
+		t.Errorf("expected messages %q, got %q", expectedMessages, got)
+	}
+
+	for _, leaked := range []string{secret, runnerToken, "some.signed.jwt"} {
+		if strings.Contains(logs, leaked) {
+			t.Errorf("expected %q to be masked in logs:\n%s", leaked, logs)
+		}
+	}
+	for _, logged := range []string{`"status":201`, `"latency":`, `"url":"` + server.URL + `/gitlab-runners"`} {
+		if !strings.Contains(logs, logged) {
+			t.Errorf("expected %s in logs:\n%s", logged, logs)
+		}
+	}
+}
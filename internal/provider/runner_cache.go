@@ -0,0 +1,136 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+// runnerCache serves reads of runners from a single List call, so that a
+// refresh of many runners doesn't make a request for each of them. It lives
+// as long as the provider, i.e. for one Terraform run.
+type runnerCache struct {
+	client *runrs.ClientWithResponses
+
+	mu sync.Mutex
+
+	// filled is whether the cache has been filled, or failed to.
+	filled  bool
+	runners map[string]runrs.GitLabRunner
+
+	// written holds the UUIDs of runners which have been written since the
+	// cache was filled, or while it was being filled, which are never served
+	// from the cache.
+	written map[string]bool
+}
+
+// newRunnerCache returns an empty runnerCache, which is filled by the first
+// get.
+func newRunnerCache(client *runrs.ClientWithResponses) *runnerCache {
+	return &runnerCache{
+		client:  client,
+		written: map[string]bool{},
+	}
+}
+
+// get returns the cached runner with the given UUID, or false if it has to be
+// read from runrs; either because it wasn't listed, has been written since,
+// or listing runners failed.
+func (c *runnerCache) get(ctx context.Context, uuid string) (*runrs.GitLabRunner, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.filled {
+		c.fill(ctx)
+	}
+
+	if c.written[uuid] {
+		return nil, false
+	}
+
+	runner, ok := c.runners[uuid]
+	if !ok {
+		return nil, false
+	}
+
+	return &runner, true
+}
+
+// fill lists all runners once. If listing fails, runners are read one by one
+// instead.
+func (c *runnerCache) fill(ctx context.Context) {
+	c.filled = true
+
+	runners, err := c.list(ctx)
+	if err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("unable to list GitLabRunners, reading them one by one: %s", err))
+		return
+	}
+
+	c.runners = make(map[string]runrs.GitLabRunner, len(runners))
+	for _, runner := range runners {
+		if runner.Uuid != nil {
+			c.runners[runner.Uuid.String()] = runner
+		}
+	}
+
+	tflog.Debug(ctx, fmt.Sprintf("listed %d GitLabRunners", len(c.runners)))
+}
+
+// list lists all runners. The spec of runrs declares a single GitLabRunner as
+// the response of list, while runrs lists all runners in an array, which
+// ListWithResponse can't parse, so the response is parsed here in either
+// shape.
+func (c *runnerCache) list(ctx context.Context) ([]runrs.GitLabRunner, error) {
+	resp, err := c.client.List(withOperation(ctx, "list"))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr runrs.Error
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Msg != "" {
+			return nil, fmt.Errorf("%s (%s)", apiErr.Msg, resp.Status)
+		}
+		return nil, fmt.Errorf("unexpected response (%s)", resp.Status)
+	}
+
+	var runners []runrs.GitLabRunner
+	if err := json.Unmarshal(body, &runners); err == nil {
+		return runners, nil
+	}
+
+	var runner runrs.GitLabRunner
+	if err := json.Unmarshal(body, &runner); err != nil {
+		return nil, fmt.Errorf("unexpected list of runners: %w", err)
+	}
+
+	return []runrs.GitLabRunner{runner}, nil
+}
+
+// invalidate stops serving the runner with the given UUID from the cache.
+// It must be called before the runner is written, so that a List in flight
+// can't bring back the old runner.
+func (c *runnerCache) invalidate(uuid string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.written[uuid] = true
+	delete(c.runners, uuid)
+}
@@ -0,0 +1,138 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+const (
+	testCachedRunnerUuid  = "be924fdd-fb28-468c-8c70-1f0ed3af4485"
+	testUnknownRunnerUuid = "0f9bd4a5-6c39-4a4e-9c5e-6e6f1b7a2c1d"
+)
+
+// newTestRunnerCache returns a runnerCache which lists runners from a runrs
+// server answering with the given status and body, and counts the lists.
+func newTestRunnerCache(t *testing.T, status int, body string) (*runnerCache, *atomic.Int32) {
+	t.Helper()
+
+	var lists atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/gitlab-runners/list" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		lists.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := runrs.NewClientWithResponses(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return newRunnerCache(client), &lists
+}
+
+func TestRunnerCache(t *testing.T) {
+	ctx := context.Background()
+
+	cache, lists := newTestRunnerCache(t, http.StatusOK, `[{
+		"uuid": "`+testCachedRunnerUuid+`",
+		"id": 1,
+		"url": "https://gitlab.com",
+		"token": "glrt-test1_abcdefXYZ",
+		"docker_image": "alpine:latest"
+	}]`)
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			runner, ok := cache.get(ctx, testCachedRunnerUuid)
+			if !ok || runner.Id != 1 {
+				t.Errorf("expected cached runner, got %v", runner)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, ok := cache.get(ctx, testUnknownRunnerUuid); ok {
+		t.Error("expected unlisted runner not to be cached")
+	}
+
+	cache.invalidate(testCachedRunnerUuid)
+	if _, ok := cache.get(ctx, testCachedRunnerUuid); ok {
+		t.Error("expected written runner not to be cached")
+	}
+
+	if n := lists.Load(); n != 1 {
+		t.Errorf("expected 1 list, got %d", n)
+	}
+}
+
+func TestRunnerCacheSingleRunner(t *testing.T) {
+	// The spec of runrs declares a single GitLabRunner as the response of
+	// list.
+	cache, _ := newTestRunnerCache(t, http.StatusOK, `{
+		"uuid": "`+testCachedRunnerUuid+`",
+		"id": 1,
+		"url": "https://gitlab.com",
+		"token": "glrt-test1_abcdefXYZ",
+		"docker_image": "alpine:latest"
+	}`)
+
+	runner, ok := cache.get(context.Background(), testCachedRunnerUuid)
+	if !ok || runner.Id != 1 {
+		t.Errorf("expected cached runner, got %v", runner)
+	}
+}
+
+func TestRunnerCacheListFailed(t *testing.T) {
+	ctx := context.Background()
+
+	testCases := map[string]struct {
+		status int
+		body   string
+	}{
+		"error": {
+			status: http.StatusInternalServerError,
+			body:   `{"err_type": "InternalError", "msg": "docker is unreachable"}`,
+		},
+		"not runners": {
+			status: http.StatusOK,
+			body:   `"docker is unreachable"`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cache, lists := newTestRunnerCache(t, testCase.status, testCase.body)
+
+			for range 2 {
+				if _, ok := cache.get(ctx, testCachedRunnerUuid); ok {
+					t.Error("expected runner not to be cached")
+				}
+			}
+
+			if n := lists.Load(); n != 1 {
+				t.Errorf("expected 1 list, got %d", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// credentialExpiryMargin is how long before its expiry a credential is
+// obtained again, so that it doesn't expire in flight.
+const credentialExpiryMargin = 30 * time.Second
+
+// runrsCredential is what requests to runrs are authenticated with: either a
+// secret which JWTs are signed with, or a ready-made bearer token.
+type runrsCredential struct {
+	secret      string
+	bearerToken string
+
+	// expiresAt is when the credential has to be obtained again, or zero if
+	// it doesn't expire.
+	expiresAt time.Time
+}
+
+// runrsAuth authenticates requests to runrs with a JWT signed with the
+// secret of its credential, which is signed again when it expires, or with
+// the bearer token of its credential. JWTs have the scope claim of the scopes
+// the request is signed for.
+type runrsAuth struct {
+	// credential returns the current credential.
+	credential func(ctx context.Context) (runrsCredential, error)
+
+	// claims are added to the JWTs.
+	claims jwt.MapClaims
+
+	mu sync.Mutex
+
+	// signed are the JWTs signed so far, by their scope claim.
+	signed map[string]signedJWT
+}
+
+// signedJWT is a JWT signed by runrsAuth.
+type signedJWT struct {
+	token     string
+	secret    string
+	expiresAt time.Time
+}
+
+// newRunrsAuth returns a runrsAuth for the credential, whose JWTs carry the
+// claims.
+func newRunrsAuth(
+	credential func(ctx context.Context) (runrsCredential, error),
+	claims jwt.MapClaims,
+) *runrsAuth {
+	return &runrsAuth{credential: credential, claims: claims, signed: map[string]signedJWT{}}
+}
+
+// staticCredential returns the credential of a token which doesn't expire.
+func staticCredential(token string) func(ctx context.Context) (runrsCredential, error) {
+	return func(context.Context) (runrsCredential, error) {
+		return runrsCredential{secret: token}, nil
+	}
+}
+
+// noCredential returns the credential of auth modes which don't sign JWTs,
+// which fails.
+func noCredential(mode string) func(ctx context.Context) (runrsCredential, error) {
+	return func(context.Context) (runrsCredential, error) {
+		return runrsCredential{}, fmt.Errorf("auth_mode %q has no secret", mode)
+	}
+}
+
+// Intercept sets the Authorization header of requests to runrs, with a JWT
+// for the scopes the request is signed for.
+func (a *runrsAuth) Intercept(ctx context.Context, req *http.Request) error {
+	token, err := a.bearerToken(ctx, strings.Join(requestScopes(req), " "))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return nil
+}
+
+// bearerToken returns the bearer token of the credential, or a JWT signed
+// with its secret which has the scope claim, unless scope is empty. Bearer
+// tokens of the credential can't be limited to scopes.
+func (a *runrsAuth) bearerToken(ctx context.Context, scope string) (string, error) {
+	credential, err := a.credential(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if credential.bearerToken != "" {
+		return credential.bearerToken, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+
+	// JWTs are signed again a minute before they expire, so that they don't
+	// expire in flight.
+	signed, ok := a.signed[scope]
+	if ok && signed.secret == credential.secret && now.Before(signed.expiresAt.Add(-time.Minute)) {
+		return signed.token, nil
+	}
+
+	expiresAt := now.Add(defaultJWTLifetime)
+	if !credential.expiresAt.IsZero() && credential.expiresAt.Before(expiresAt) {
+		expiresAt = credential.expiresAt
+	}
+
+	// The scope claim is only ever the scope the request is signed for, not
+	// one of the configured claims.
+	claims := jwt.MapClaims{}
+	for name, value := range a.claims {
+		if name != "scope" {
+			claims[name] = value
+		}
+	}
+	if scope != "" {
+		claims["scope"] = scope
+	}
+
+	token, err := signJWT(credential.secret, claims, expiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	a.signed[scope] = signedJWT{token: token, secret: credential.secret, expiresAt: expiresAt}
+
+	return token, nil
+}
+
+// secret returns the secret of the credential, which fails for bearer
+// tokens.
+func (a *runrsAuth) secret(ctx context.Context) (string, error) {
+	credential, err := a.credential(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if credential.secret == "" {
+		return "", errors.New("the credential is a bearer token, not a secret")
+	}
+
+	return credential.secret, nil
+}
@@ -0,0 +1,199 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+// minRunrsVersion is the oldest runrs the provider supports, which is the
+// version of runrs-api.json the client is generated from.
+var minRunrsVersion = version.Must(version.NewVersion("0.6.2"))
+
+// runrsSpecPath is where runrs serves its OpenAPI spec, relative to its
+// endpoint. runrs has no version endpoint, so its version is read from its
+// spec, if it serves one there.
+const runrsSpecPath = "api-docs/runrs-api.json"
+
+// runnerPropertyVersions are the properties of GitLabRunners with the runrs
+// version which introduced them.
+var runnerPropertyVersions = map[string]*version.Version{
+	"docker_image":      minRunrsVersion,
+	"id":                minRunrsVersion,
+	"name":              minRunrsVersion,
+	"token":             minRunrsVersion,
+	"token_obtained_at": minRunrsVersion,
+	"url":               minRunrsVersion,
+	"uuid":              minRunrsVersion,
+}
+
+// runrsInfo is what the provider knows about the runrs it talks to.
+type runrsInfo struct {
+	version *version.Version
+
+	// runnerProperties are the properties of GitLabRunners which runrs
+	// supports.
+	runnerProperties map[string]bool
+}
+
+// newRunrsInfo returns the capabilities of the given version of runrs.
+func newRunrsInfo(runrsVersion *version.Version) *runrsInfo {
+	info := &runrsInfo{
+		version:          runrsVersion,
+		runnerProperties: map[string]bool{},
+	}
+	for property, since := range runnerPropertyVersions {
+		info.runnerProperties[property] = runrsVersion.GreaterThanOrEqual(since)
+	}
+
+	return info
+}
+
+// supportsRunnerProperty returns whether runrs supports the property of
+// GitLabRunners. Without a configured provider, runrs is assumed to be the
+// oldest version the provider supports.
+func (i *runrsInfo) supportsRunnerProperty(property string) bool {
+	if i == nil {
+		i = newRunrsInfo(minRunrsVersion)
+	}
+
+	return i.runnerProperties[property]
+}
+
+// runnerAttributeProperties are the optional attributes of
+// peripheral_gitlab_runner with the GitLabRunner property they are sent to
+// runrs as, so that attributes runrs doesn't support fail at plan time rather
+// than with a 400 at apply time.
+var runnerAttributeProperties = []struct {
+	attribute string
+	property  string
+	value     func(*GitLabRunnerResourceModel) attr.Value
+}{
+	{"name", "name", func(m *GitLabRunnerResourceModel) attr.Value { return m.Name }},
+}
+
+// unsupportedRunnerAttributes returns an error for each attribute set in the
+// plan which runrs doesn't support.
+func (i *runrsInfo) unsupportedRunnerAttributes(plan *GitLabRunnerResourceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	runrsVersion := minRunrsVersion
+	if i != nil {
+		runrsVersion = i.version
+	}
+
+	for _, attribute := range runnerAttributeProperties {
+		if attribute.value(plan).IsNull() || i.supportsRunnerProperty(attribute.property) {
+			continue
+		}
+
+		diags.AddAttributeError(
+			path.Root(attribute.attribute),
+			"Unsupported Attribute",
+			fmt.Sprintf(
+				"runrs %s doesn't support %s, which needs runrs %s or later. Upgrade runrs, or "+
+					"remove the attribute.",
+				runrsVersion,
+				attribute.attribute,
+				runnerPropertyVersions[attribute.property],
+			),
+		)
+	}
+
+	return diags
+}
+
+// fetchRunrsVersion reads the version of runrs from the spec it serves at
+// endpoint. The version is nil if runrs doesn't serve its spec.
+func fetchRunrsVersion(ctx context.Context, doer runrs.HttpRequestDoer, endpoint string) (*version.Version, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		strings.TrimSuffix(endpoint, "/")+"/"+runrsSpecPath,
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doer.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+
+	var spec struct {
+		Info struct {
+			Version string `json:"version"`
+		} `json:"info"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&spec); err != nil {
+		return nil, fmt.Errorf("invalid spec: %w", err)
+	}
+
+	runrsVersion, err := version.NewVersion(spec.Info.Version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version: %w", err)
+	}
+
+	return runrsVersion, nil
+}
+
+// checkRunrsVersion fails if the runrs at endpoint is too old, and returns
+// its version and capabilities otherwise. If its version can't be read, or
+// runrs doesn't serve its spec, runrs is assumed to be the oldest version the
+// provider supports.
+func checkRunrsVersion(ctx context.Context, doer runrs.HttpRequestDoer, endpoint string) (*runrsInfo, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	runrsVersion, err := fetchRunrsVersion(ctx, doer, endpoint)
+	switch {
+	case err != nil:
+		diags.AddWarning(
+			"Unable to Determine runrs Version",
+			fmt.Sprintf(
+				"Unable to read the version of runrs from its spec, assuming runrs %s: %s",
+				minRunrsVersion,
+				err,
+			),
+		)
+		return newRunrsInfo(minRunrsVersion), diags
+	case runrsVersion == nil:
+		tflog.Info(ctx, fmt.Sprintf("runrs serves no spec, assuming runrs %s", minRunrsVersion))
+		return newRunrsInfo(minRunrsVersion), diags
+	case runrsVersion.LessThan(minRunrsVersion):
+		diags.AddError(
+			"Unsupported runrs Version",
+			fmt.Sprintf(
+				"runrs %s is older than %s, the oldest version this provider supports. Upgrade runrs.",
+				runrsVersion,
+				minRunrsVersion,
+			),
+		)
+		return nil, diags
+	}
+
+	tflog.Debug(ctx, fmt.Sprintf("runrs version %s", runrsVersion))
+
+	return newRunrsInfo(runrsVersion), diags
+}
@@ -0,0 +1,201 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+
+	"terraform-provider-peripheral/internal/runrstest"
+)
+
+func TestFetchRunrsVersion(t *testing.T) {
+	server := runrstest.NewServer(testRunrsSecret)
+	t.Cleanup(server.Close)
+
+	runrsVersion, err := fetchRunrsVersion(context.Background(), http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !runrsVersion.Equal(minRunrsVersion) {
+		t.Errorf("expected version %s, got %s", minRunrsVersion, runrsVersion)
+	}
+
+	// runrs which doesn't serve its spec has no known version.
+	noSpec := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(noSpec.Close)
+
+	runrsVersion, err = fetchRunrsVersion(context.Background(), http.DefaultClient, noSpec.URL)
+	if err != nil || runrsVersion != nil {
+		t.Errorf("expected no version and no error, got %v and %v", runrsVersion, err)
+	}
+}
+
+func TestFetchRunrsVersionFailover(t *testing.T) {
+	server := runrstest.NewServer(testRunrsSecret)
+	t.Cleanup(server.Close)
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	failover, err := newEndpointFailover(http.DefaultClient, []string{unreachable.URL, server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The spec is requested from the first endpoint, and served by the
+	// second.
+	runrsVersion, err := fetchRunrsVersion(context.Background(), failover, unreachable.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !runrsVersion.Equal(minRunrsVersion) {
+		t.Errorf("expected version %s, got %s", minRunrsVersion, runrsVersion)
+	}
+}
+
+func TestProviderConfigureRunrsVersion(t *testing.T) {
+	testCases := map[string]struct {
+		version          string
+		expectedSeverity tfprotov6.DiagnosticSeverity
+		expectedSummary  string
+	}{
+		"supported": {
+			version: minRunrsVersion.String(),
+		},
+		"newer": {
+			version: "1.0.0",
+		},
+		"too old": {
+			version:          "0.5.0",
+			expectedSeverity: tfprotov6.DiagnosticSeverityError,
+			expectedSummary:  "Unsupported runrs Version",
+		},
+		"invalid": {
+			version:          "latest",
+			expectedSeverity: tfprotov6.DiagnosticSeverityWarning,
+			expectedSummary:  "Unable to Determine runrs Version",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			server := runrstest.NewServer(testRunrsSecret)
+			server.SetVersion(testCase.version)
+			t.Cleanup(server.Close)
+
+			_, diags := testConfigureProvider(t, map[string]tftypes.Value{
+				"endpoint": tftypes.NewValue(tftypes.String, server.URL),
+			})
+
+			if testCase.expectedSummary == "" {
+				if len(diags) > 0 {
+					t.Errorf("unexpected diagnostics: %v", diags)
+				}
+				return
+			}
+
+			if len(diags) != 1 {
+				t.Fatalf("expected 1 diagnostic, got %d: %v", len(diags), diags)
+			}
+			if diags[0].Severity != testCase.expectedSeverity || diags[0].Summary != testCase.expectedSummary {
+				t.Errorf("expected %s %q, got %s %q", testCase.expectedSeverity, testCase.expectedSummary, diags[0].Severity, diags[0].Summary)
+			}
+		})
+	}
+}
+
+func TestCheckRunrsVersionUnknown(t *testing.T) {
+	noSpec := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(noSpec.Close)
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(broken.Close)
+
+	testCases := map[string]struct {
+		endpoint         string
+		expectedWarnings int
+	}{
+		"no spec": {
+			endpoint: noSpec.URL,
+		},
+		"unreadable spec": {
+			endpoint:         broken.URL,
+			expectedWarnings: 1,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			info, diags := checkRunrsVersion(context.Background(), http.DefaultClient, testCase.endpoint)
+			if diags.HasError() || diags.WarningsCount() != testCase.expectedWarnings {
+				t.Errorf("expected %d warnings, got %v", testCase.expectedWarnings, diags)
+			}
+
+			// runrs of unknown version is assumed to be the oldest one
+			// supported.
+			if info == nil || !info.version.Equal(minRunrsVersion) {
+				t.Fatalf("expected runrs %s, got %v", minRunrsVersion, info)
+			}
+			if !info.supportsRunnerProperty("name") {
+				t.Error("expected name to be supported")
+			}
+		})
+	}
+}
+
+func TestRunrsInfoUnsupportedRunnerAttributes(t *testing.T) {
+	older := newRunrsInfo(version.Must(version.NewVersion("0.6.0")))
+
+	testCases := map[string]struct {
+		info          *runrsInfo
+		name          types.String
+		expectedError bool
+	}{
+		"supported": {
+			info: newRunrsInfo(minRunrsVersion),
+			name: types.StringValue("runner"),
+		},
+		"unset": {
+			info: older,
+			name: types.StringNull(),
+		},
+		"unsupported": {
+			info:          older,
+			name:          types.StringValue("runner"),
+			expectedError: true,
+		},
+		"unknown version": {
+			name: types.StringValue("runner"),
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			diags := testCase.info.unsupportedRunnerAttributes(&GitLabRunnerResourceModel{Name: testCase.name})
+
+			if diags.HasError() != testCase.expectedError {
+				t.Fatalf("expected error to be %t, got %v", testCase.expectedError, diags)
+			}
+			if testCase.expectedError {
+				withPath, ok := diags[0].(interface{ Path() path.Path })
+				if !ok || !withPath.Path().Equal(path.Root("name")) {
+					t.Errorf("expected error for name, got %v", diags[0])
+				}
+			}
+		})
+	}
+}
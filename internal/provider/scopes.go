@@ -0,0 +1,90 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+// Scopes of the api_token security scheme of runrs, which JWTs are limited
+// to.
+const (
+	scopeRead  = "gitlab-runners:read"
+	scopeWrite = "gitlab-runners:write"
+)
+
+// allScopes are the scopes JWTs may have, which is the default of
+// allowed_scopes.
+var allScopes = []string{scopeRead, scopeWrite}
+
+// operationScopes are the scopes each operation of runrs needs, by its
+// operationId.
+var operationScopes = map[string]string{
+	"create": scopeWrite,
+	"list":   scopeRead,
+	"read":   scopeRead,
+	"update": scopeWrite,
+	"delete": scopeWrite,
+}
+
+// scopesKey is the type of requestScopesKey.
+type scopesKey string
+
+// requestScopesKey is the context key of the scopes of the api_token security
+// scheme a request to runrs is signed for, like runrs.Api_tokenScopes is for
+// the server side.
+const requestScopesKey = scopesKey(runrs.Api_tokenScopes)
+
+// operationKey is the type of requestOperationKey.
+type operationKey string
+
+// requestOperationKey is the context key of the operationId of a request to
+// runrs.
+const requestOperationKey = operationKey("operationId")
+
+// withOperation returns a context for requests to runrs for the operation
+// with the given operationId, which their scope is looked up by.
+func withOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, requestOperationKey, operation)
+}
+
+// scopeRequest returns a RequestEditorFn which records the scope of the
+// operation of each request, for the JWT it is signed with. Requests for
+// operations whose scope isn't allowed fail before they are sent, so that a
+// plan-only pipeline can't change runners even against a runrs which doesn't
+// enforce scopes. So do requests without a known operation, rather than
+// being signed for a scope which might not fit.
+func scopeRequest(allowed []string) runrs.RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		operation, _ := ctx.Value(requestOperationKey).(string)
+		scope, ok := operationScopes[operation]
+		if !ok {
+			return fmt.Errorf("request to runrs for unknown operation %q: %s %s", operation, req.Method, req.URL.Path)
+		}
+
+		if !slices.Contains(allowed, scope) {
+			return fmt.Errorf(
+				"%s of runners needs the scope %s, which allowed_scopes doesn't include",
+				operation,
+				scope,
+			)
+		}
+
+		*req = *req.WithContext(context.WithValue(req.Context(), requestScopesKey, []string{scope}))
+
+		return nil
+	}
+}
+
+// requestScopes returns the scopes the request is signed for, or nil if it
+// isn't limited to any.
+func requestScopes(req *http.Request) []string {
+	scopes, _ := req.Context().Value(requestScopesKey).([]string)
+	return scopes
+}
@@ -0,0 +1,256 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	uuidpkg "github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+	"terraform-provider-peripheral/internal/runrstest"
+)
+
+func TestScopeRequest(t *testing.T) {
+	testCases := map[string]struct {
+		method        string
+		path          string
+		operation     string
+		allowed       []string
+		expectedScope string
+		expectedError bool
+	}{
+		"create": {
+			method:        http.MethodPost,
+			path:          "/gitlab-runners",
+			operation:     "create",
+			allowed:       allScopes,
+			expectedScope: scopeWrite,
+		},
+		"list": {
+			method:        http.MethodGet,
+			path:          "/gitlab-runners/list",
+			operation:     "list",
+			allowed:       allScopes,
+			expectedScope: scopeRead,
+		},
+		"read": {
+			method:        http.MethodGet,
+			path:          "/gitlab-runners/be924fdd-fb28-468c-8c70-1f0ed3af4485",
+			operation:     "read",
+			allowed:       []string{scopeRead},
+			expectedScope: scopeRead,
+		},
+		"update": {
+			method:        http.MethodPut,
+			path:          "/gitlab-runners/be924fdd-fb28-468c-8c70-1f0ed3af4485",
+			operation:     "update",
+			allowed:       allScopes,
+			expectedScope: scopeWrite,
+		},
+		"delete not allowed": {
+			method:        http.MethodDelete,
+			path:          "/gitlab-runners/be924fdd-fb28-468c-8c70-1f0ed3af4485",
+			operation:     "delete",
+			allowed:       []string{scopeRead},
+			expectedError: true,
+		},
+		"unknown operation": {
+			method:        http.MethodGet,
+			path:          "/gitlab-runners/be924fdd-fb28-468c-8c70-1f0ed3af4485",
+			operation:     "rotate",
+			allowed:       allScopes,
+			expectedError: true,
+		},
+		"no operation": {
+			method:        http.MethodGet,
+			path:          "/gitlab-runners/be924fdd-fb28-468c-8c70-1f0ed3af4485",
+			allowed:       allScopes,
+			expectedError: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if testCase.operation != "" {
+				ctx = withOperation(ctx, testCase.operation)
+			}
+
+			req, err := http.NewRequestWithContext(ctx, testCase.method, "http://localhost:3000"+testCase.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = scopeRequest(testCase.allowed)(ctx, req)
+
+			if testCase.expectedError {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if scopes := requestScopes(req); len(scopes) != 1 || scopes[0] != testCase.expectedScope {
+				t.Errorf("expected scopes [%s], got %v", testCase.expectedScope, scopes)
+			}
+		})
+	}
+}
+
+func TestRunrsAuthScope(t *testing.T) {
+	// Configured claims can't override the scope of the request.
+	auth := newRunrsAuth(staticCredential(testRunrsSecret), jwt.MapClaims{"scope": scopeWrite})
+
+	tokens := map[string]string{}
+	for _, scope := range []string{scopeRead, scopeWrite, scopeRead} {
+		token, err := auth.bearerToken(context.Background(), scope)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		claims := jwt.MapClaims{}
+		if _, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+			return []byte(testRunrsSecret), nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if claims["scope"] != scope {
+			t.Errorf("expected scope claim %q, got %v", scope, claims["scope"])
+		}
+
+		if previous, ok := tokens[scope]; ok && previous != token {
+			t.Errorf("expected the JWT for %s to be reused", scope)
+		}
+		tokens[scope] = token
+	}
+
+	if tokens[scopeRead] == tokens[scopeWrite] {
+		t.Error("expected a JWT per scope")
+	}
+}
+
+func TestAllowedScopes(t *testing.T) {
+	server := runrstest.NewServer(testRunrsSecret)
+	t.Cleanup(server.Close)
+
+	runner := runrs.GitLabRunner{
+		Id:          42,
+		Url:         "https://gitlab.com/",
+		Token:       "glrt-0123456789-abcdefXYZ",
+		DockerImage: "alpine:latest",
+	}
+
+	testCases := map[string]struct {
+		allowed       []string
+		expectCreated bool
+	}{
+		"read and write": {
+			allowed:       allScopes,
+			expectCreated: true,
+		},
+		"plan only": {
+			allowed: []string{scopeRead},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			auth := newRunrsAuth(staticCredential(testRunrsSecret), jwt.MapClaims{})
+			client, err := runrs.NewClientWithResponses(
+				server.URL,
+				runrs.WithRequestEditorFn(scopeRequest(testCase.allowed)),
+				runrs.WithRequestEditorFn(auth.Intercept),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// runrs accepts reads with JWTs for the read scope only.
+			read, err := client.ReadWithResponse(withOperation(context.Background(), "read"), uuidpkg.Nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if read.StatusCode() != http.StatusNotFound {
+				t.Errorf("expected status %d, got %d: %s", http.StatusNotFound, read.StatusCode(), read.Body)
+			}
+
+			runnersBefore := len(server.Runners())
+			runner.Id++
+
+			created, err := client.CreateWithResponse(withOperation(context.Background(), "create"), runner)
+
+			if !testCase.expectCreated {
+				if err == nil || !strings.Contains(err.Error(), "allowed_scopes") {
+					t.Errorf("expected create to fail before it is sent, got %v", err)
+				}
+				if len(server.Runners()) != runnersBefore {
+					t.Error("expected no runner to be created")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created.JSON201 == nil {
+				t.Errorf("unexpected create response %d: %s", created.StatusCode(), created.Body)
+			}
+		})
+	}
+}
+
+func TestProviderConfigureAllowedScopes(t *testing.T) {
+	server := runrstest.NewServer(testRunrsSecret)
+	t.Cleanup(server.Close)
+
+	testCases := map[string]struct {
+		scopes          []string
+		expectedSummary string
+	}{
+		"read": {
+			scopes: []string{scopeRead},
+		},
+		"read and write": {
+			scopes: []string{scopeRead, scopeWrite},
+		},
+		"write": {
+			scopes:          []string{scopeWrite},
+			expectedSummary: "Missing Scope",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var scopes []tftypes.Value
+			for _, scope := range testCase.scopes {
+				scopes = append(scopes, tftypes.NewValue(tftypes.String, scope))
+			}
+
+			_, diags := testConfigureProvider(t, map[string]tftypes.Value{
+				"endpoint":       tftypes.NewValue(tftypes.String, server.URL),
+				"allowed_scopes": tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, scopes),
+			})
+
+			if testCase.expectedSummary == "" {
+				if len(diags) > 0 {
+					t.Errorf("unexpected diagnostics: %v", diags)
+				}
+				return
+			}
+
+			if len(diags) != 1 || diags[0].Summary != testCase.expectedSummary {
+				t.Errorf("expected %q, got %v", testCase.expectedSummary, diags)
+			}
+		})
+	}
+}
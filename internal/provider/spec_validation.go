@@ -0,0 +1,291 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+// strictValidationEnvVar enables strict_validation when it isn't set in the
+// provider configuration.
+const strictValidationEnvVar = "PERIPHERAL_STRICT_VALIDATION"
+
+// specValidator validates requests to runrs and their responses against the
+// OpenAPI spec the client was generated from. Mismatches don't fail requests,
+// but are reported as warnings of the operation which sent them.
+type specValidator struct {
+	doer   runrs.HttpRequestDoer
+	router routers.Router
+}
+
+// newSpecValidator returns a specValidator which sends requests through doer
+// to the given endpoints.
+func newSpecValidator(doer runrs.HttpRequestDoer, endpoints []string) (*specValidator, error) {
+	spec, err := runrs.GetSwagger()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load spec: %w", err)
+	}
+
+	// The spec only knows the development server, so routes are matched for
+	// the configured endpoints instead.
+	spec.Servers = nil
+	for _, endpoint := range endpoints {
+		spec.Servers = append(spec.Servers, &openapi3.Server{URL: strings.TrimSuffix(endpoint, "/")})
+	}
+
+	router, err := legacy.NewRouter(spec)
+	if err != nil {
+		return nil, fmt.Errorf("unable to route spec: %w", err)
+	}
+
+	return &specValidator{doer: doer, router: router}, nil
+}
+
+func (v *specValidator) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	route, pathParams, err := v.router.FindRoute(req)
+	if err != nil {
+		reportSpecMismatch(ctx, fmt.Sprintf(
+			"The request %s %s is not in the runrs spec: %s",
+			req.Method,
+			req.URL.Path,
+			err,
+		))
+		return v.doer.Do(req)
+	}
+
+	input := &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: pathParams,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+			IncludeResponseStatus: true,
+			MultiError:            true,
+		},
+	}
+
+	// Validation consumes the body, so it is validated on a copy.
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		validated := req.Clone(ctx)
+		validated.Body = body
+		input.Request = validated
+	}
+
+	if err := openapi3filter.ValidateRequest(ctx, input); err != nil {
+		location := "#/paths/" + escapeJSONPointer(route.Path) + "/" + strings.ToLower(route.Method) +
+			"/requestBody/content/application~1json/schema"
+
+		var schema *openapi3.SchemaRef
+		if route.Operation.RequestBody != nil && route.Operation.RequestBody.Value != nil {
+			if mediaType := route.Operation.RequestBody.Value.Content.Get("application/json"); mediaType != nil {
+				schema = mediaType.Schema
+			}
+		}
+
+		for _, mismatch := range specMismatchesOf(err, location, schema) {
+			reportSpecMismatch(ctx, fmt.Sprintf(
+				"The request %s %s doesn't match the runrs spec at %s",
+				route.Method,
+				route.Path,
+				mismatch,
+			))
+		}
+	}
+
+	resp, err := v.doer.Do(req)
+	if err != nil {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	responseInput := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 resp.StatusCode,
+		Header:                 resp.Header,
+		Options:                input.Options,
+	}
+	responseInput.SetBodyBytes(body)
+
+	if err := openapi3filter.ValidateResponse(ctx, responseInput); err != nil {
+		status := strconv.Itoa(resp.StatusCode)
+		location := "#/paths/" + escapeJSONPointer(route.Path) + "/" + strings.ToLower(route.Method) +
+			"/responses/" + status + "/content/application~1json/schema"
+
+		var schema *openapi3.SchemaRef
+		if response := route.Operation.Responses.Status(resp.StatusCode); response != nil && response.Value != nil {
+			if mediaType := response.Value.Content.Get("application/json"); mediaType != nil {
+				schema = mediaType.Schema
+			}
+		} else {
+			location = "#/paths/" + escapeJSONPointer(route.Path) + "/" + strings.ToLower(route.Method) +
+				"/responses"
+		}
+
+		for _, mismatch := range specMismatchesOf(err, location, schema) {
+			reportSpecMismatch(ctx, fmt.Sprintf(
+				"The response %s of runrs to %s %s doesn't match the runrs spec at %s",
+				status,
+				route.Method,
+				route.Path,
+				mismatch,
+			))
+		}
+	}
+
+	return resp, nil
+}
+
+// specMismatchesOf describes the mismatches in a validation error, each
+// starting with the path of the schema it failed. location is the path of
+// schema, which is the schema of the validated body, if there is one.
+func specMismatchesOf(err error, location string, schema *openapi3.SchemaRef) []string {
+	var multiErr openapi3.MultiError
+	if errors.As(err, &multiErr) {
+		var mismatches []string
+		for _, err := range multiErr {
+			mismatches = append(mismatches, specMismatchesOf(err, location, schema)...)
+		}
+		return mismatches
+	}
+
+	var schemaErr *openapi3.SchemaError
+	if !errors.As(err, &schemaErr) {
+		var requestErr *openapi3filter.RequestError
+		if errors.As(err, &requestErr) && requestErr.Parameter != nil {
+			location = strings.TrimSuffix(location, "/requestBody/content/application~1json/schema") +
+				"/parameters/" + escapeJSONPointer(requestErr.Parameter.Name)
+		}
+		return []string{fmt.Sprintf("%s: %s", location, err)}
+	}
+
+	pointer := schemaErr.JSONPointer()
+	if schemaErr.SchemaField == "required" && len(pointer) > 0 {
+		// The pointer names the missing property, which the object schema
+		// requires.
+		pointer = pointer[:len(pointer)-1]
+	}
+
+	location = schemaPath(schema, location, pointer)
+	if schemaErr.SchemaField != "" {
+		location += "/" + escapeJSONPointer(schemaErr.SchemaField)
+	}
+
+	value := "body"
+	if len(schemaErr.JSONPointer()) > 0 {
+		value = "/" + strings.Join(schemaErr.JSONPointer(), "/")
+	}
+
+	return []string{fmt.Sprintf("%s: %s %s", location, value, schemaErr.Reason)}
+}
+
+// schemaPath returns the path of the schema which validates the value at
+// pointer, starting from schema at location. Referenced schemas are named by
+// their reference.
+func schemaPath(schema *openapi3.SchemaRef, location string, pointer []string) string {
+	for _, token := range pointer {
+		if schema == nil || schema.Value == nil {
+			return location
+		}
+		if schema.Ref != "" {
+			location = schema.Ref
+		}
+
+		if property, ok := schema.Value.Properties[token]; ok {
+			location += "/properties/" + escapeJSONPointer(token)
+			schema = property
+		} else if schema.Value.Items != nil {
+			location += "/items"
+			schema = schema.Value.Items
+		} else {
+			return location
+		}
+	}
+
+	if schema != nil && schema.Ref != "" {
+		location = schema.Ref
+	}
+
+	return location
+}
+
+// escapeJSONPointer escapes a token of a JSON pointer.
+func escapeJSONPointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// specMismatchesKey is the context key of the specMismatches.
+type specMismatchesKey struct{}
+
+// specMismatches collects the mismatches of an operation's requests.
+type specMismatches struct {
+	mu    sync.Mutex
+	diags diag.Diagnostics
+}
+
+// collectSpecMismatches returns a context whose requests' mismatches with the
+// runrs spec are collected, to be reported by specMismatchWarnings.
+func collectSpecMismatches(ctx context.Context) context.Context {
+	return context.WithValue(ctx, specMismatchesKey{}, &specMismatches{})
+}
+
+// specMismatchWarnings returns a warning for each mismatch with the runrs spec
+// collected in ctx.
+func specMismatchWarnings(ctx context.Context) diag.Diagnostics {
+	mismatches, ok := ctx.Value(specMismatchesKey{}).(*specMismatches)
+	if !ok {
+		return nil
+	}
+
+	mismatches.mu.Lock()
+	defer mismatches.mu.Unlock()
+
+	return mismatches.diags
+}
+
+// reportSpecMismatch collects the mismatch in ctx, or logs it if mismatches
+// aren't collected.
+func reportSpecMismatch(ctx context.Context, detail string) {
+	mismatches, ok := ctx.Value(specMismatchesKey{}).(*specMismatches)
+	if !ok {
+		tflog.Warn(ctx, "runrs spec mismatch", map[string]any{"detail": detail})
+		return
+	}
+
+	mismatches.mu.Lock()
+	defer mismatches.mu.Unlock()
+
+	mismatches.diags.AddWarning(
+		"runrs Spec Mismatch",
+		detail+". The provider and runrs may be out of sync, which can lead to incorrect state.",
+	)
+}
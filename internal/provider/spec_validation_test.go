@@ -0,0 +1,108 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	uuidpkg "github.com/google/uuid"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+func TestSpecValidator(t *testing.T) {
+	testCases := map[string]struct {
+		status           int
+		body             string
+		runner           runrs.GitLabRunner
+		expectedWarnings []string
+	}{
+		"valid": {
+			status: http.StatusOK,
+			body: `{"id": 1, "url": "https://gitlab.com", "token": "glrt-test1_abcdefXYZ",
+				"docker_image": "alpine:latest", "name": "runner"}`,
+			runner: runrs.GitLabRunner{Id: 1, Url: "https://gitlab.com", Token: "glrt-test1_abcdefXYZ"},
+		},
+		"invalid response": {
+			status: http.StatusOK,
+			body:   `{"id": 1, "url": "https://gitlab.com", "docker_image": "alpine:latest", "name": "runner"}`,
+			runner: runrs.GitLabRunner{Id: 1, Url: "https://gitlab.com", Token: "glrt-test1_abcdefXYZ"},
+			expectedWarnings: []string{
+				"The response 200 of runrs to PUT /gitlab-runners/{uuid} doesn't match the runrs spec " +
+					"at #/components/schemas/GitLabRunner/required: /token property \"token\" is missing",
+			},
+		},
+		"invalid error": {
+			status: http.StatusNotFound,
+			body:   `{"err_type": "Gone", "msg": "no such runner"}`,
+			runner: runrs.GitLabRunner{Id: 1, Url: "https://gitlab.com", Token: "glrt-test1_abcdefXYZ"},
+			expectedWarnings: []string{
+				"The response 404 of runrs to PUT /gitlab-runners/{uuid} doesn't match the runrs spec " +
+					"at #/components/schemas/ErrorType/enum: /err_type value is not one of the allowed values",
+			},
+		},
+		"invalid request": {
+			status: http.StatusOK,
+			body: `{"id": 1, "url": "https://gitlab.com", "token": "glrt-test1_abcdefXYZ",
+				"docker_image": "alpine:latest", "name": "runner"}`,
+			runner: runrs.GitLabRunner{Id: -1, Url: "https://gitlab.com", Token: "glrt-test1_abcdefXYZ"},
+			expectedWarnings: []string{
+				"The request PUT /gitlab-runners/{uuid} doesn't match the runrs spec " +
+					"at #/components/schemas/GitLabRunner/properties/id/minimum: /id number must be at least 0",
+			},
+		},
+		"undocumented status": {
+			status: http.StatusTeapot,
+			body:   `{"err_type": "Other", "msg": "teapot"}`,
+			runner: runrs.GitLabRunner{Id: 1, Url: "https://gitlab.com", Token: "glrt-test1_abcdefXYZ"},
+			expectedWarnings: []string{
+				"The response 418 of runrs to PUT /gitlab-runners/{uuid} doesn't match the runrs spec " +
+					"at #/paths/~1gitlab-runners~1{uuid}/put/responses",
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(testCase.status)
+				_, _ = w.Write([]byte(testCase.body))
+			}))
+			t.Cleanup(server.Close)
+
+			validator, err := newSpecValidator(http.DefaultClient, []string{server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client, err := runrs.NewClientWithResponses(server.URL, runrs.WithHTTPClient(validator))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := collectSpecMismatches(context.Background())
+			if _, err := client.UpdateWithResponse(ctx, uuidpkg.MustParse(testCachedRunnerUuid), testCase.runner); err != nil {
+				t.Fatal(err)
+			}
+
+			warnings := specMismatchWarnings(ctx)
+			if warnings.HasError() {
+				t.Fatalf("expected only warnings, got %v", warnings)
+			}
+			if len(warnings) != len(testCase.expectedWarnings) {
+				t.Fatalf("expected %d warnings, got %d: %v", len(testCase.expectedWarnings), len(warnings), warnings)
+			}
+			for i, expected := range testCase.expectedWarnings {
+				if !strings.HasPrefix(warnings[i].Detail(), expected) {
+					t.Errorf("expected warning %q, got %q", expected, warnings[i].Detail())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// tokenCommand obtains the credential for runrs from the output of a
+// command, which is cached until it expires.
+//
+// The command prints either the secret as plain text, or a JSON object with
+// the secret or a bearer token, and optionally when it expires:
+//
+//	{"secret": "...", "expires_at": "2024-08-23T23:23:23Z"}
+//	{"bearer_token": "...", "expires_at": "2024-08-23T23:23:23Z"}
+type tokenCommand struct {
+	argv []string
+
+	mu        sync.Mutex
+	cached    runrsCredential
+	hasCached bool
+}
+
+// tokenCommandOutput is the JSON output of token_command.
+type tokenCommandOutput struct {
+	Secret      string    `json:"secret"`
+	BearerToken string    `json:"bearer_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// newTokenCommand returns a tokenCommand which runs argv.
+func newTokenCommand(argv []string) *tokenCommand {
+	return &tokenCommand{argv: argv}
+}
+
+// credential returns the cached credential, or runs the command if there is
+// none or it expires soon.
+func (c *tokenCommand) credential(ctx context.Context) (runrsCredential, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.hasCached && (c.cached.expiresAt.IsZero() || time.Until(c.cached.expiresAt) > credentialExpiryMargin) {
+		return c.cached, nil
+	}
+
+	credential, err := c.run(ctx)
+	if err != nil {
+		return runrsCredential{}, err
+	}
+
+	c.cached, c.hasCached = credential, true
+
+	return credential, nil
+}
+
+// run runs the command and parses its output.
+func (c *tokenCommand) run(ctx context.Context) (runrsCredential, error) {
+	tflog.Debug(ctx, "running token_command", map[string]interface{}{
+		"command": c.argv[0],
+	})
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, c.argv[0], c.argv[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return runrsCredential{}, fmt.Errorf("%s: %w: %s", c.argv[0], err, msg)
+		}
+		return runrsCredential{}, fmt.Errorf("%s: %w", c.argv[0], err)
+	}
+
+	output := bytes.TrimSpace(stdout.Bytes())
+	if len(output) == 0 {
+		return runrsCredential{}, fmt.Errorf("%s printed nothing", c.argv[0])
+	}
+
+	if output[0] != '{' {
+		return runrsCredential{secret: string(output)}, nil
+	}
+
+	var parsed tokenCommandOutput
+	if err := json.Unmarshal(output, &parsed); err != nil {
+		return runrsCredential{}, fmt.Errorf("invalid output of %s: %w", c.argv[0], err)
+	}
+
+	if (parsed.Secret == "") == (parsed.BearerToken == "") {
+		return runrsCredential{}, errors.New("the output of token_command must have exactly one of secret or bearer_token")
+	}
+
+	return runrsCredential{
+		secret:      parsed.Secret,
+		bearerToken: parsed.BearerToken,
+		expiresAt:   parsed.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,237 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+
+	"terraform-provider-peripheral/internal/runrstest"
+)
+
+func TestTokenCommand(t *testing.T) {
+	testCases := map[string]struct {
+		script        string
+		expected      runrsCredential
+		expectedError string
+	}{
+		"plain text": {
+			script:   "echo ' warblgarbl '",
+			expected: runrsCredential{secret: "warblgarbl"},
+		},
+		"json secret": {
+			script: `echo '{"secret": "warblgarbl", "expires_at": "2024-08-23T23:23:23Z"}'`,
+			expected: runrsCredential{
+				secret:    "warblgarbl",
+				expiresAt: time.Date(2024, 8, 23, 23, 23, 23, 0, time.UTC),
+			},
+		},
+		"json bearer token": {
+			script:   `echo '{"bearer_token": "eyJhbGciOiJIUzI1NiJ9"}'`,
+			expected: runrsCredential{bearerToken: "eyJhbGciOiJIUzI1NiJ9"},
+		},
+		"json with both": {
+			script:        `echo '{"secret": "warblgarbl", "bearer_token": "eyJhbGciOiJIUzI1NiJ9"}'`,
+			expectedError: "exactly one of secret or bearer_token",
+		},
+		"invalid json": {
+			script:        `echo '{"secret": '`,
+			expectedError: "invalid output",
+		},
+		"no output": {
+			script:        "true",
+			expectedError: "printed nothing",
+		},
+		"failure": {
+			script:        "echo 'vault is sealed' >&2; exit 1",
+			expectedError: "vault is sealed",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			credential, err := newTokenCommand([]string{"sh", "-c", testCase.script}).credential(context.Background())
+
+			if testCase.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+					t.Errorf("expected error containing %q, got %v", testCase.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if credential.secret != testCase.expected.secret ||
+				credential.bearerToken != testCase.expected.bearerToken ||
+				!credential.expiresAt.Equal(testCase.expected.expiresAt) {
+				t.Errorf("expected %+v, got %+v", testCase.expected, credential)
+			}
+		})
+	}
+}
+
+func TestTokenCommandCache(t *testing.T) {
+	testCases := map[string]struct {
+		expiresIn          time.Duration
+		expectedExecutions int
+	}{
+		"no expiry": {
+			expectedExecutions: 1,
+		},
+		"valid": {
+			expiresIn:          time.Hour,
+			expectedExecutions: 1,
+		},
+		"expiring": {
+			expiresIn:          credentialExpiryMargin / 2,
+			expectedExecutions: 3,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			counter := filepath.Join(t.TempDir(), "executions")
+
+			output := `echo warblgarbl`
+			if testCase.expiresIn != 0 {
+				expiresAt := time.Now().Add(testCase.expiresIn).UTC().Format(time.RFC3339)
+				output = `echo '{"secret": "warblgarbl", "expires_at": "` + expiresAt + `"}'`
+			}
+
+			command := newTokenCommand([]string{"sh", "-c", "echo >> " + counter + "; " + output})
+			for range 3 {
+				if _, err := command.credential(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			executions, err := os.ReadFile(counter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(executions) != testCase.expectedExecutions {
+				t.Errorf("expected %d executions, got %d", testCase.expectedExecutions, len(executions))
+			}
+		})
+	}
+}
+
+func TestRunrsAuth(t *testing.T) {
+	expiresAt := time.Now().Add(10 * time.Minute)
+	secret := runrsCredential{secret: testRunrsSecret, expiresAt: expiresAt}
+
+	credential := secret
+	auth := newRunrsAuth(func(context.Context) (runrsCredential, error) {
+		return credential, nil
+	}, jwt.MapClaims{"sub": "ci"})
+
+	bearerToken := func() string {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, "http://localhost", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := auth.Intercept(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+
+		return strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	}
+
+	signed := bearerToken()
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(signed, claims, func(*jwt.Token) (interface{}, error) {
+		return []byte(testRunrsSecret), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if claims["sub"] != "ci" {
+		t.Errorf("expected claims of the profile, got %v", claims)
+	}
+	if exp, err := claims.GetExpirationTime(); err != nil || exp.Unix() != expiresAt.Unix() {
+		t.Errorf("expected the JWT to expire with the secret at %s, got %v", expiresAt, exp)
+	}
+
+	if again := bearerToken(); again != signed {
+		t.Error("expected the JWT to be reused while it is valid")
+	}
+
+	credential = runrsCredential{secret: "rotated"}
+	if rotated := bearerToken(); rotated == signed {
+		t.Error("expected the JWT to be signed again with a rotated secret")
+	}
+
+	credential = runrsCredential{bearerToken: "eyJhbGciOiJIUzI1NiJ9"}
+	if token := bearerToken(); token != credential.bearerToken {
+		t.Errorf("expected bearer token %q, got %q", credential.bearerToken, token)
+	}
+	if _, err := auth.secret(context.Background()); err == nil {
+		t.Error("expected no secret for a bearer token")
+	}
+}
+
+func TestProviderConfigureTokenCommand(t *testing.T) {
+	server := runrstest.NewServer(testRunrsSecret)
+	t.Cleanup(server.Close)
+
+	bearerToken, err := signJWT(testRunrsSecret, jwt.MapClaims{}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		script          string
+		expectedSummary string
+	}{
+		"secret": {
+			script: "echo " + testRunrsSecret,
+		},
+		"bearer token": {
+			script: `echo '{"bearer_token": "` + bearerToken + `"}'`,
+		},
+		"wrong secret": {
+			script:          "echo wrong",
+			expectedSummary: "Authentication Failed",
+		},
+		"failure": {
+			script:          "exit 1",
+			expectedSummary: "Token Command Failed",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, diags := testConfigureProvider(t, map[string]tftypes.Value{
+				"endpoint": tftypes.NewValue(tftypes.String, server.URL),
+				"token":    tftypes.NewValue(tftypes.String, nil),
+				"token_command": tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, []tftypes.Value{
+					tftypes.NewValue(tftypes.String, "sh"),
+					tftypes.NewValue(tftypes.String, "-c"),
+					tftypes.NewValue(tftypes.String, testCase.script),
+				}),
+			})
+
+			if testCase.expectedSummary == "" {
+				if len(diags) > 0 {
+					t.Errorf("unexpected diagnostics: %v", diags)
+				}
+				return
+			}
+
+			if len(diags) != 1 || diags[0].Summary != testCase.expectedSummary {
+				t.Errorf("expected %q, got %v", testCase.expectedSummary, diags)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	uuidpkg "github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+// tracerName is the name of the tracer of the provider, and the default
+// service name of its traces.
+const tracerName = "terraform-provider-peripheral"
+
+// runnerUuidAttribute is the span attribute of the UUID of a runner.
+const runnerUuidAttribute = attribute.Key("peripheral.runner.uuid")
+
+// tracer returns the tracer of the provider, which doesn't record anything
+// unless SetupTracing has set up an exporter.
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// SetupTracing sets up tracing from the standard OTEL_* environment
+// variables. OTEL_TRACES_EXPORTER selects the exporter:
+//
+//   - "otlp" exports through OTLP, over OTEL_EXPORTER_OTLP_TRACES_PROTOCOL or
+//     OTEL_EXPORTER_OTLP_PROTOCOL, which is either "http/protobuf" (default)
+//     or "grpc", to OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or
+//     OTEL_EXPORTER_OTLP_ENDPOINT.
+//   - "file" writes spans as JSON lines to OTEL_EXPORTER_FILE_PATH.
+//   - "none", or unset, disables tracing.
+//
+// The returned function flushes and stops tracing.
+func SetupTracing(ctx context.Context, version string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var closeFile func() error
+
+	switch name := os.Getenv("OTEL_TRACES_EXPORTER"); name {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		protocol := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL")
+		if protocol == "" {
+			protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+		}
+
+		var err error
+		switch protocol {
+		case "", "http/protobuf":
+			exporter, err = otlptracehttp.New(ctx)
+		case "grpc":
+			exporter, err = otlptracegrpc.New(ctx)
+		default:
+			return nil, fmt.Errorf("unsupported OTLP protocol %q", protocol)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to set up OTLP exporter: %w", err)
+		}
+	case "file":
+		path := os.Getenv("OTEL_EXPORTER_FILE_PATH")
+		if path == "" {
+			return nil, errors.New("OTEL_EXPORTER_FILE_PATH must be set for the file exporter")
+		}
+
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open trace file: %w", err)
+		}
+		closeFile = file.Close
+
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("unable to set up file exporter: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q", name)
+	}
+
+	// Attributes from OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES take
+	// precedence over the defaults.
+	resource, err := sdkresource.New(
+		ctx,
+		sdkresource.WithAttributes(
+			semconv.ServiceName(tracerName),
+			semconv.ServiceVersion(version),
+		),
+		sdkresource.WithTelemetrySDK(),
+		sdkresource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to set up trace resource: %w", err)
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource),
+	)
+	otel.SetTracerProvider(tracerProvider)
+
+	return func(ctx context.Context) error {
+		err := tracerProvider.Shutdown(ctx)
+		if closeFile != nil {
+			err = errors.Join(err, closeFile())
+		}
+		return err
+	}, nil
+}
+
+// startSpan starts the span of a resource operation.
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name)
+}
+
+// endSpan ends the span of a resource operation, which failed if there are
+// errors.
+func endSpan(span trace.Span, diags diag.Diagnostics) {
+	for _, d := range diags.Errors() {
+		span.RecordError(fmt.Errorf("%s: %s", d.Summary(), d.Detail()))
+	}
+	if diags.HasError() {
+		span.SetStatus(codes.Error, diags.Errors()[0].Summary())
+	}
+
+	span.End()
+}
+
+// requestSpanKey is the context key of the span of a request to runrs.
+type requestSpanKey struct{}
+
+// traceRequest starts the span of a request to runrs, and propagates it to
+// runrs through the traceparent header. The span ends once requestTracer got
+// the response.
+func traceRequest(ctx context.Context, req *http.Request) error {
+	ctx, span := tracer().Start(
+		ctx,
+		"runrs "+req.Method+" "+requestRoute(req),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(req.URL.String()),
+			semconv.ServerAddress(req.URL.Hostname()),
+		),
+	)
+
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	*req = *req.WithContext(context.WithValue(ctx, requestSpanKey{}, span))
+
+	return nil
+}
+
+// requestRoute returns the path of the request with the runner UUID replaced,
+// so that span names don't differ by runner.
+func requestRoute(req *http.Request) string {
+	segments := strings.Split(req.URL.Path, "/")
+	for i, segment := range segments {
+		if _, err := uuidpkg.Parse(segment); err == nil {
+			segments[i] = "{uuid}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// requestTracer ends the spans which traceRequest started.
+type requestTracer struct {
+	doer runrs.HttpRequestDoer
+}
+
+// newRequestTracer returns a requestTracer which sends requests through doer.
+func newRequestTracer(doer runrs.HttpRequestDoer) *requestTracer {
+	return &requestTracer{doer: doer}
+}
+
+func (t *requestTracer) Do(req *http.Request) (*http.Response, error) {
+	resp, err := t.doer.Do(req)
+
+	span, ok := req.Context().Value(requestSpanKey{}).(trace.Span)
+	if !ok {
+		return resp, err
+	}
+	defer span.End()
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+
+	return resp, nil
+}
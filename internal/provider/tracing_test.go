@@ -0,0 +1,135 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	uuidpkg "github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+// testSpanRecorder records the spans of the provider until the test ends.
+func testSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+
+	tracerProvider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(tracerProvider)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	return recorder
+}
+
+func TestRequestTracing(t *testing.T) {
+	recorder := testSpanRecorder(t)
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"err_type": "NotFound", "msg": "no such runner"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := runrs.NewClientWithResponses(
+		server.URL,
+		runrs.WithRequestEditorFn(traceRequest),
+		runrs.WithHTTPClient(newRequestTracer(http.DefaultClient)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, span := startSpan(context.Background(), "peripheral_gitlab_runner.Read")
+	if _, err := client.ReadWithResponse(ctx, uuidpkg.MustParse(testCachedRunnerUuid)); err != nil {
+		t.Fatal(err)
+	}
+	endSpan(span, diag.Diagnostics{diag.NewErrorDiagnostic("Client Error", "no such runner")})
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	requestSpan, operationSpan := spans[0], spans[1]
+
+	if name := requestSpan.Name(); name != "runrs GET /gitlab-runners/{uuid}" {
+		t.Errorf("unexpected request span name %q", name)
+	}
+	if requestSpan.Parent().SpanID() != operationSpan.SpanContext().SpanID() {
+		t.Error("expected request span to be a child of the operation span")
+	}
+	if !strings.Contains(traceparent, requestSpan.SpanContext().SpanID().String()) {
+		t.Errorf("expected traceparent of the request span, got %q", traceparent)
+	}
+	if operationSpan.Status().Code != codes.Error {
+		t.Errorf("expected operation span to have failed, got %v", operationSpan.Status())
+	}
+}
+
+func TestSetupTracingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "file")
+	t.Setenv("OTEL_EXPORTER_FILE_PATH", path)
+	t.Setenv("OTEL_SERVICE_NAME", "peripheral-test")
+
+	tracerProvider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(tracerProvider)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	ctx := context.Background()
+
+	shutdown, err := SetupTracing(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, span := startSpan(ctx, "peripheral_gitlab_runner.Create")
+	endSpan(span, nil)
+
+	if err := shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	traces, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{`"Name":"peripheral_gitlab_runner.Create"`, `"Value":"peripheral-test"`} {
+		if !strings.Contains(string(traces), expected) {
+			t.Errorf("expected %s in traces:\n%s", expected, traces)
+		}
+	}
+}
+
+func TestSetupTracingInvalid(t *testing.T) {
+	t.Setenv("OTEL_TRACES_EXPORTER", "zipkin")
+
+	if _, err := SetupTracing(context.Background(), "test"); err == nil {
+		t.Error("expected unsupported exporter to fail")
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package runrstest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenExchangePath is where the Server exchanges ID tokens of a trusted
+// Issuer for access tokens, as in RFC 8693.
+const TokenExchangePath = "/oauth/token"
+
+// tokenExchangeLifetime is how long access tokens from token exchanges are
+// valid.
+const tokenExchangeLifetime = 5 * time.Minute
+
+// Issuer is a stand-in OIDC issuer, like GitLab issuing id_tokens to CI jobs,
+// which signs ID tokens with an RSA key.
+type Issuer struct {
+	// URL is the iss claim of the ID tokens.
+	URL string
+
+	key *rsa.PrivateKey
+}
+
+// NewIssuer returns an Issuer with a new key.
+func NewIssuer() *Issuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("runrstest: failed to generate key: %v", err))
+	}
+
+	return &Issuer{URL: "https://gitlab.example.com", key: key}
+}
+
+// IDToken returns an ID token for the audience which expires at expiresAt.
+func (i *Issuer) IDToken(audience string, expiresAt time.Time) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss": i.URL,
+		"sub": "project_path:infra/runners:ref_type:branch:ref:main",
+		"aud": audience,
+		"iat": time.Now().Unix(),
+		"exp": expiresAt.Unix(),
+	}).SignedString(i.key)
+}
+
+// verify checks that the ID token was issued by the Issuer for the audience.
+func (i *Issuer) verify(token, audience string) error {
+	_, err := jwt.Parse(
+		token,
+		func(*jwt.Token) (any, error) { return &i.key.PublicKey, nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(i.URL),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+	return err
+}
+
+// trust is the Issuer the Server trusts, and the audience its ID tokens must
+// be for.
+type trust struct {
+	mu        sync.Mutex
+	issuer    *Issuer
+	audience  string
+	exchanges int
+}
+
+// TrustIssuer makes the server accept ID tokens of the issuer for the
+// audience, both as bearer tokens and in token exchanges.
+func (s *Server) TrustIssuer(issuer *Issuer, audience string) {
+	s.trust.mu.Lock()
+	defer s.trust.mu.Unlock()
+
+	s.trust.issuer = issuer
+	s.trust.audience = audience
+}
+
+// TokenExchanges returns how many ID tokens the server exchanged.
+func (s *Server) TokenExchanges() int {
+	s.trust.mu.Lock()
+	defer s.trust.mu.Unlock()
+
+	return s.trust.exchanges
+}
+
+// verifyIDToken checks that the token is an ID token of the trusted issuer.
+func (s *Server) verifyIDToken(token string) error {
+	s.trust.mu.Lock()
+	issuer, audience := s.trust.issuer, s.trust.audience
+	s.trust.mu.Unlock()
+
+	if issuer == nil {
+		return errors.New("no trusted issuer")
+	}
+
+	return issuer.verify(token, audience)
+}
+
+// exchange exchanges an ID token of the trusted issuer for an access token
+// signed with the secret of the server.
+func (s *Server) exchange(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, "invalid_request", err.Error())
+		return
+	}
+	if grantType := r.PostForm.Get("grant_type"); grantType != "urn:ietf:params:oauth:grant-type:token-exchange" {
+		writeOAuthError(w, "unsupported_grant_type", fmt.Sprintf("unsupported grant_type %q", grantType))
+		return
+	}
+	if tokenType := r.PostForm.Get("subject_token_type"); tokenType != "urn:ietf:params:oauth:token-type:id_token" {
+		writeOAuthError(w, "invalid_request", fmt.Sprintf("unsupported subject_token_type %q", tokenType))
+		return
+	}
+	if err := s.verifyIDToken(r.PostForm.Get("subject_token")); err != nil {
+		writeOAuthError(w, "invalid_grant", err.Error())
+		return
+	}
+
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss": "runrs",
+		"exp": time.Now().Add(tokenExchangeLifetime).Unix(),
+	}).SignedString([]byte(s.Secret))
+	if err != nil {
+		writeOAuthError(w, "server_error", err.Error())
+		return
+	}
+
+	s.trust.mu.Lock()
+	s.trust.exchanges++
+	s.trust.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token":      accessToken,
+		"issued_token_type": "urn:ietf:params:oauth:token-type:access_token",
+		"token_type":        "Bearer",
+		"expires_in":        int(tokenExchangeLifetime.Seconds()),
+	})
+}
+
+// writeOAuthError writes an error response of RFC 6749.
+func writeOAuthError(w http.ResponseWriter, code, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
@@ -0,0 +1,126 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package runrstest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerTrustIssuer(t *testing.T) {
+	server := NewServer(testSecret)
+	t.Cleanup(server.Close)
+
+	issuer := NewIssuer()
+	server.TrustIssuer(issuer, "runrs")
+
+	testCases := map[string]struct {
+		audience       string
+		expectedStatus int
+	}{
+		"trusted": {
+			audience:       "runrs",
+			expectedStatus: http.StatusOK,
+		},
+		"wrong audience": {
+			audience:       "vault",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			idToken, err := issuer.IDToken(testCase.audience, time.Now().Add(time.Minute))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := testClientWithToken(t, server, idToken).List(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != testCase.expectedStatus {
+				t.Errorf("expected status %d, got %d", testCase.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestServerTokenExchange(t *testing.T) {
+	server := NewServer(testSecret)
+	t.Cleanup(server.Close)
+
+	issuer := NewIssuer()
+	server.TrustIssuer(issuer, "runrs")
+
+	testCases := map[string]struct {
+		audience      string
+		expectedError string
+	}{
+		"trusted": {
+			audience: "runrs",
+		},
+		"wrong audience": {
+			audience:      "vault",
+			expectedError: "invalid_grant",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			idToken, err := issuer.IDToken(testCase.audience, time.Now().Add(time.Minute))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := http.PostForm(server.URL+TokenExchangePath, url.Values{
+				"grant_type":         {"urn:ietf:params:oauth:grant-type:token-exchange"},
+				"subject_token":      {idToken},
+				"subject_token_type": {"urn:ietf:params:oauth:token-type:id_token"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			var body struct {
+				AccessToken string `json:"access_token"`
+				Error       string `json:"error"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+
+			if testCase.expectedError != "" {
+				if body.Error != testCase.expectedError {
+					t.Errorf("expected error %q, got %+v", testCase.expectedError, body)
+				}
+				return
+			}
+
+			if !strings.HasPrefix(body.AccessToken, "ey") {
+				t.Fatalf("expected an access token, got %+v", body)
+			}
+
+			list, err := testClientWithToken(t, server, body.AccessToken).List(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			list.Body.Close()
+			if list.StatusCode != http.StatusOK {
+				t.Errorf("expected the access token to be accepted, got %d", list.StatusCode)
+			}
+		})
+	}
+
+	if server.TokenExchanges() != 1 {
+		t.Errorf("expected 1 token exchange, got %d", server.TokenExchanges())
+	}
+}
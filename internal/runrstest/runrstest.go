@@ -0,0 +1,429 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+// Package runrstest provides an in-memory runrs for tests, which serves the
+// runrs API on a local httptest server.
+package runrstest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	uuidpkg "github.com/google/uuid"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+// Operation is a runrs API operation, named by its operationId.
+type Operation string
+
+// Operations of the runrs API.
+const (
+	OperationCreate Operation = "create"
+	OperationList   Operation = "list"
+	OperationRead   Operation = "read"
+	OperationUpdate Operation = "update"
+	OperationDelete Operation = "delete"
+)
+
+// Scopes of the api_token security scheme of runrs. JWTs with a scope claim
+// are only accepted for the operations of their scopes.
+const (
+	ScopeRead  = "gitlab-runners:read"
+	ScopeWrite = "gitlab-runners:write"
+)
+
+// Server is an in-memory runrs, which only accepts requests with a JWT
+// signed with its secret, or with an ID token of the Issuer it trusts.
+type Server struct {
+	*httptest.Server
+
+	// Secret is the secret which JWTs are verified with.
+	Secret string
+
+	mu      sync.Mutex
+	version string
+	runners map[uuidpkg.UUID]runrs.GitLabRunner
+	errors  map[Operation]runrs.ErrorType
+
+	trust trust
+}
+
+// NewServer starts a Server with the given secret. The caller should Close
+// it when finished.
+func NewServer(secret string) *Server {
+	s := &Server{
+		Secret:  secret,
+		runners: map[uuidpkg.UUID]runrs.GitLabRunner{},
+		errors:  map[Operation]runrs.ErrorType{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api-docs/runrs-api.json", s.spec)
+	mux.HandleFunc(TokenExchangePath, s.exchange)
+	mux.HandleFunc("/gitlab-runners", s.authenticated(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		s.create(w, r)
+	}))
+	mux.HandleFunc("/gitlab-runners/", s.authenticated(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/gitlab-runners/")
+
+		if name == "list" {
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			s.list(w)
+			return
+		}
+
+		uuid, err := uuidpkg.Parse(name)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, runrs.InvalidArgument, fmt.Sprintf("invalid UUID %q", name))
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			s.read(w, uuid)
+		case http.MethodPut:
+			s.update(w, r, uuid)
+		case http.MethodDelete:
+			s.delete(w, uuid)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// spec serves the spec of runrs, like runrs does without authentication.
+func (s *Server) spec(w http.ResponseWriter, r *http.Request) {
+	spec, err := runrs.GetSwagger()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, runrs.InternalError, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	if s.version != "" {
+		spec.Info.Version = s.version
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, spec)
+}
+
+// SetVersion sets the version of runrs in the spec the server serves, which
+// defaults to the version of the spec the client is generated from.
+func (s *Server) SetVersion(version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.version = version
+}
+
+// InjectError makes every following request of the operation fail with the
+// error type, until ClearErrors is called.
+func (s *Server) InjectError(operation Operation, errType runrs.ErrorType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.errors[operation] = errType
+}
+
+// ClearErrors stops failing requests with injected errors.
+func (s *Server) ClearErrors() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.errors = map[Operation]runrs.ErrorType{}
+}
+
+// Runner returns the runner with the given UUID, if there is one.
+func (s *Server) Runner(uuid string) (runrs.GitLabRunner, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parsed, err := uuidpkg.Parse(uuid)
+	if err != nil {
+		return runrs.GitLabRunner{}, false
+	}
+
+	runner, ok := s.runners[parsed]
+	return runner, ok
+}
+
+// Runners returns all runners.
+func (s *Server) Runners() []runrs.GitLabRunner {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runners := make([]runrs.GitLabRunner, 0, len(s.runners))
+	for _, runner := range s.runners {
+		runners = append(runners, runner)
+	}
+
+	return runners
+}
+
+// StatusForError returns the status runrs answers with for the error type.
+func StatusForError(errType runrs.ErrorType) int {
+	switch errType {
+	case runrs.NotFound:
+		return http.StatusNotFound
+	case runrs.AlreadyExists, runrs.BadRequest, runrs.InvalidArgument:
+		return http.StatusBadRequest
+	case runrs.Forbidden:
+		return http.StatusForbidden
+	case runrs.Unchanged:
+		return http.StatusNoContent
+	case runrs.Unimplemented:
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ScopeOf returns the scope the operation needs.
+func ScopeOf(operation Operation) string {
+	switch operation {
+	case OperationList, OperationRead:
+		return ScopeRead
+	default:
+		return ScopeWrite
+	}
+}
+
+// authenticated only passes on requests with a valid JWT or ID token, and
+// fails those with an injected error.
+func (s *Server) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			writeError(w, http.StatusUnauthorized, runrs.Forbidden, "missing bearer token")
+			return
+		}
+
+		claims := jwt.MapClaims{}
+		_, err := jwt.ParseWithClaims(
+			token,
+			claims,
+			func(*jwt.Token) (any, error) { return []byte(s.Secret), nil },
+			jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+			jwt.WithExpirationRequired(),
+		)
+		if err != nil && s.verifyIDToken(token) == nil {
+			err = nil
+		}
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, runrs.Forbidden, fmt.Sprintf("invalid token: %s", err))
+			return
+		}
+
+		operation := operationOf(r)
+		if scope, ok := claims["scope"].(string); ok && !slices.Contains(strings.Fields(scope), ScopeOf(operation)) {
+			writeError(
+				w,
+				http.StatusForbidden,
+				runrs.Forbidden,
+				fmt.Sprintf("%s needs scope %s, token has %q", operation, ScopeOf(operation), scope),
+			)
+			return
+		}
+
+		if errType, ok := s.injectedError(operation); ok {
+			writeError(w, StatusForError(errType), errType, "injected error")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (s *Server) injectedError(operation Operation) (runrs.ErrorType, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	errType, ok := s.errors[operation]
+	return errType, ok
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	var runner runrs.GitLabRunner
+	if err := json.NewDecoder(r.Body).Decode(&runner); err != nil {
+		writeError(w, http.StatusBadRequest, runrs.BadRequest, fmt.Sprintf("invalid body: %s", err))
+		return
+	}
+	if msg := validate(runner); msg != "" {
+		writeError(w, http.StatusBadRequest, runrs.InvalidArgument, msg)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.runners {
+		if existing.Url == runner.Url && existing.Id == runner.Id {
+			writeError(
+				w,
+				http.StatusBadRequest,
+				runrs.AlreadyExists,
+				fmt.Sprintf("runner with ID %d already exists for %s", runner.Id, runner.Url),
+			)
+			return
+		}
+	}
+
+	uuid := uuidpkg.New()
+	runner.Uuid = &uuid
+
+	if runner.Name == nil || *runner.Name == "" {
+		name := "runner-" + uuid.String()[:8]
+		runner.Name = &name
+	}
+	if runner.TokenObtainedAt == nil {
+		now := time.Now().UTC().Truncate(time.Second)
+		runner.TokenObtainedAt = &now
+	}
+
+	s.runners[uuid] = runner
+
+	writeJSON(w, http.StatusCreated, runner)
+}
+
+// list lists all runners as an array like runrs does, rather than as the
+// single GitLabRunner its spec declares.
+func (s *Server) list(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, s.Runners())
+}
+
+func (s *Server) read(w http.ResponseWriter, uuid uuidpkg.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runner, ok := s.runners[uuid]
+	if !ok {
+		writeError(w, http.StatusNotFound, runrs.NotFound, fmt.Sprintf("no runner with UUID %s", uuid))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, runner)
+}
+
+func (s *Server) update(w http.ResponseWriter, r *http.Request, uuid uuidpkg.UUID) {
+	var runner runrs.GitLabRunner
+	if err := json.NewDecoder(r.Body).Decode(&runner); err != nil {
+		writeError(w, http.StatusBadRequest, runrs.BadRequest, fmt.Sprintf("invalid body: %s", err))
+		return
+	}
+	if msg := validate(runner); msg != "" {
+		writeError(w, http.StatusBadRequest, runrs.InvalidArgument, msg)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.runners[uuid]
+	if !ok {
+		writeError(w, http.StatusNotFound, runrs.NotFound, fmt.Sprintf("no runner with UUID %s", uuid))
+		return
+	}
+
+	runner.Uuid = &uuid
+
+	if runner.Name == nil || *runner.Name == "" {
+		runner.Name = existing.Name
+	}
+	if runner.TokenObtainedAt == nil {
+		runner.TokenObtainedAt = existing.TokenObtainedAt
+		if runner.Token != existing.Token {
+			now := time.Now().UTC().Truncate(time.Second)
+			runner.TokenObtainedAt = &now
+		}
+	}
+
+	s.runners[uuid] = runner
+
+	writeJSON(w, http.StatusOK, runner)
+}
+
+func (s *Server) delete(w http.ResponseWriter, uuid uuidpkg.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runner, ok := s.runners[uuid]
+	if !ok {
+		writeError(w, http.StatusNotFound, runrs.NotFound, fmt.Sprintf("no runner with UUID %s", uuid))
+		return
+	}
+
+	delete(s.runners, uuid)
+
+	writeJSON(w, http.StatusOK, runner)
+}
+
+// operationOf returns the operation of the request.
+func operationOf(r *http.Request) Operation {
+	switch {
+	case r.Method == http.MethodPost:
+		return OperationCreate
+	case r.URL.Path == "/gitlab-runners/list":
+		return OperationList
+	case r.Method == http.MethodPut:
+		return OperationUpdate
+	case r.Method == http.MethodDelete:
+		return OperationDelete
+	default:
+		return OperationRead
+	}
+}
+
+// validate returns why the runner is invalid, or "" if it is valid.
+func validate(runner runrs.GitLabRunner) string {
+	var missing []string
+	if runner.Url == "" {
+		missing = append(missing, "url")
+	}
+	if runner.Token == "" {
+		missing = append(missing, "token")
+	}
+	if runner.DockerImage == "" {
+		missing = append(missing, "docker_image")
+	}
+
+	if len(missing) > 0 {
+		return fmt.Sprintf("missing %s", strings.Join(missing, ", "))
+	}
+
+	return ""
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, errType runrs.ErrorType, msg string) {
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return
+	}
+
+	writeJSON(w, status, runrs.Error{ErrType: errType, Msg: msg})
+}
@@ -0,0 +1,252 @@
+// Copyright (c) bmc::labs GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package runrstest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	uuidpkg "github.com/google/uuid"
+
+	runrs "terraform-provider-peripheral/internal/clients"
+)
+
+const testSecret = "warblgarbl"
+
+// testClient returns a client for the server which authenticates with a JWT
+// signed with secret.
+func testClient(t *testing.T, server *Server, secret string) *runrs.ClientWithResponses {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return testClientWithToken(t, server, token)
+}
+
+// testClientWithToken returns a client for the server which authenticates
+// with the bearer token.
+func testClientWithToken(t *testing.T, server *Server, token string) *runrs.ClientWithResponses {
+	t.Helper()
+
+	client, err := runrs.NewClientWithResponses(
+		server.URL,
+		runrs.WithRequestEditorFn(func(_ context.Context, req *http.Request) error {
+			req.Header.Set("Authorization", "Bearer "+token)
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+func TestServer(t *testing.T) {
+	server := NewServer(testSecret)
+	t.Cleanup(server.Close)
+
+	client := testClient(t, server, testSecret)
+	ctx := context.Background()
+
+	runner := runrs.GitLabRunner{
+		Id:          42,
+		Url:         "https://gitlab.com/",
+		Token:       "glrt-0123456789-abcdefXYZ",
+		DockerImage: "alpine:latest",
+	}
+
+	created, err := client.CreateWithResponse(ctx, runner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.JSON201 == nil {
+		t.Fatalf("unexpected create response %d: %s", created.StatusCode(), created.Body)
+	}
+	if created.JSON201.Uuid == nil || created.JSON201.Name == nil || created.JSON201.TokenObtainedAt == nil {
+		t.Fatalf("expected uuid, name and token_obtained_at to be set, got %+v", created.JSON201)
+	}
+	uuid := *created.JSON201.Uuid
+
+	duplicate, err := client.CreateWithResponse(ctx, runner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiErr := duplicate.GetError(); apiErr == nil || apiErr.ErrType != runrs.AlreadyExists {
+		t.Errorf("expected AlreadyExists, got %v", apiErr)
+	}
+
+	read, err := client.ReadWithResponse(ctx, uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.JSON200 == nil || read.JSON200.Token != runner.Token {
+		t.Errorf("unexpected read response %d: %s", read.StatusCode(), read.Body)
+	}
+
+	name := "renamed"
+	runner.Name = &name
+	updated, err := client.UpdateWithResponse(ctx, uuid, runner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.JSON200 == nil || *updated.JSON200.Name != name {
+		t.Errorf("unexpected update response %d: %s", updated.StatusCode(), updated.Body)
+	}
+
+	// Runners are listed as an array, not as the single GitLabRunner the spec
+	// declares, which ListWithResponse can't parse.
+	list, err := client.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var runners []runrs.GitLabRunner
+	err = json.NewDecoder(list.Body).Decode(&runners)
+	list.Body.Close()
+	if err != nil || len(runners) != 1 {
+		t.Errorf("unexpected list response %d: %v", list.StatusCode, err)
+	}
+
+	deleted, err := client.DeleteWithResponse(ctx, uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiErr := deleted.GetError(); apiErr != nil {
+		t.Errorf("unexpected delete error %v", apiErr)
+	}
+
+	missing, err := client.ReadWithResponse(ctx, uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiErr := missing.GetError(); apiErr == nil || apiErr.ErrType != runrs.NotFound {
+		t.Errorf("expected NotFound, got %v", apiErr)
+	}
+}
+
+func TestServerInvalidToken(t *testing.T) {
+	server := NewServer(testSecret)
+	t.Cleanup(server.Close)
+
+	resp, err := testClient(t, server, "wrong").ListWithResponse(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode() != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, resp.StatusCode())
+	}
+}
+
+func TestServerInjectError(t *testing.T) {
+	server := NewServer(testSecret)
+	t.Cleanup(server.Close)
+
+	client := testClient(t, server, testSecret)
+	ctx := context.Background()
+
+	for _, errType := range []runrs.ErrorType{
+		runrs.AlreadyExists,
+		runrs.BadRequest,
+		runrs.ConnectionFailed,
+		runrs.Forbidden,
+		runrs.InternalError,
+		runrs.InvalidArgument,
+		runrs.NotFound,
+		runrs.Other,
+		runrs.Unchanged,
+		runrs.Unimplemented,
+	} {
+		t.Run(string(errType), func(t *testing.T) {
+			server.InjectError(OperationRead, errType)
+			t.Cleanup(server.ClearErrors)
+
+			resp, err := client.ReadWithResponse(ctx, uuidpkg.New())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode() != StatusForError(errType) {
+				t.Errorf("expected status %d, got %d", StatusForError(errType), resp.StatusCode())
+			}
+
+			// runrs answers Unchanged without a body.
+			if errType == runrs.Unchanged {
+				return
+			}
+
+			// The generated client only decodes errors for the statuses in the
+			// spec, so the body is decoded here.
+			var apiErr runrs.Error
+			if err := json.Unmarshal(resp.Body, &apiErr); err != nil {
+				t.Fatal(err)
+			}
+			if apiErr.ErrType != errType {
+				t.Errorf("expected %s, got %s", errType, apiErr.ErrType)
+			}
+		})
+	}
+
+	list, err := client.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list.Body.Close()
+	if list.StatusCode != http.StatusOK {
+		t.Errorf("expected errors to be cleared, got %d", list.StatusCode)
+	}
+}
+
+func TestServerScope(t *testing.T) {
+	server := NewServer(testSecret)
+	t.Cleanup(server.Close)
+
+	testCases := map[string]struct {
+		scope          string
+		expectedStatus int
+	}{
+		"read": {
+			scope:          ScopeRead,
+			expectedStatus: http.StatusNotFound,
+		},
+		"read and write": {
+			scope:          ScopeRead + " " + ScopeWrite,
+			expectedStatus: http.StatusNotFound,
+		},
+		"write": {
+			scope:          ScopeWrite,
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+				"scope": testCase.scope,
+				"exp":   time.Now().Add(time.Minute).Unix(),
+			}).SignedString([]byte(testSecret))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := testClientWithToken(t, server, token).ReadWithResponse(context.Background(), uuidpkg.New())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode() != testCase.expectedStatus {
+				t.Errorf("expected status %d, got %d", testCase.expectedStatus, resp.StatusCode())
+			}
+		})
+	}
+}